@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time-tracker/internal/app"
+	"time-tracker/internal/docs"
+)
+
+func runDocs(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return errors.New("usage: admin docs check")
+	}
+
+	undocumented, err := docs.Undocumented(app.NewRouter())
+	if err != nil {
+		return err
+	}
+	if len(undocumented) > 0 {
+		for _, route := range undocumented {
+			fmt.Println(route)
+		}
+		return fmt.Errorf("%d routes are not documented in internal/docs/openapi.json", len(undocumented))
+	}
+	fmt.Println("All routes are documented.")
+	return nil
+}
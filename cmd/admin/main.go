@@ -0,0 +1,81 @@
+// Command admin runs database administration tasks against the database configured in
+// .env. Run it from the repository root:
+//
+//	go run ./cmd/admin migrate version
+//	go run ./cmd/admin migrate up
+//	go run ./cmd/admin migrate down 1
+//	go run ./cmd/admin seed -users 20 -days 14
+//	go run ./cmd/admin maintenance close-stale-timers -max-duration 12h -dry-run
+//	go run ./cmd/admin maintenance recompute
+//	go run ./cmd/admin docs check
+package main
+
+import (
+	"fmt"
+	"os"
+	"time-tracker/internal/config"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/pii"
+)
+
+const usage = `Usage: admin <command> [arguments]
+
+Commands:
+  migrate up [N]          apply all pending migrations, or the next N
+  migrate down [N]        roll back the last N migrations (1 by default), -all for every one
+  migrate goto V          migrate up or down to version V
+  migrate force V         set the version without running migrations, clearing the dirty flag
+  migrate version         print the current version
+  seed                    create demo users with time entries
+  maintenance close-stale-timers
+                          stop timers that have been running for too long
+  maintenance recompute   re-derive encrypted data, blind indexes and planner statistics
+  docs check              fail if a route is missing from the OpenAPI spec (no database needed)
+
+Run admin <command> -h for the flags of a command.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "admin:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		return nil
+	}
+	if args[0] == "docs" {
+		return runDocs(args[1:])
+	}
+
+	var command func(*config.Config, []string) error
+	switch args[0] {
+	case "migrate":
+		command = runMigrate
+	case "seed":
+		command = runSeed
+	case "maintenance":
+		command = runMaintenance
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	logger.InitLogger()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := pii.Init(cfg); err != nil {
+		return err
+	}
+	// Connect only: migrations are applied by the migrate command, not implicitly.
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
+	return command(cfg, args[1:])
+}
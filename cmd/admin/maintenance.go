@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+	"time-tracker/internal/config"
+	"time-tracker/internal/database"
+	"time-tracker/internal/models"
+)
+
+func runMaintenance(_ *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: admin maintenance close-stale-timers|recompute")
+	}
+
+	switch args[0] {
+	case "close-stale-timers":
+		return closeStaleTimers(args[1:])
+	case "recompute":
+		return recompute(args[1:])
+	default:
+		return fmt.Errorf("unknown maintenance task %q", args[0])
+	}
+}
+
+func closeStaleTimers(args []string) error {
+	flags := flag.NewFlagSet("close-stale-timers", flag.ExitOnError)
+	maxDuration := flags.Duration("max-duration", 12*time.Hour, "timers running longer than this are closed, ending at start + max-duration")
+	dryRun := flags.Bool("dry-run", false, "list the timers without closing them")
+	actor := flags.String("actor", "admin", "actor recorded in the audit log")
+	flags.Parse(args)
+
+	if *maxDuration <= 0 {
+		return errors.New("-max-duration must be positive")
+	}
+
+	tasks, err := database.CloseStaleTimers(models.Actor{Name: *actor}, *maxDuration, *dryRun)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No stale timers.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tUSER\tTITLE\tSTARTED\tCLOSED AT")
+	for _, task := range tasks {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", task.TaskID, task.UserID, task.Title,
+			task.StartTime.Format("2006-01-02 15:04"), task.EndTime.Format("2006-01-02 15:04"))
+	}
+	tw.Flush()
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d timers would be closed.\n", len(tasks))
+		return nil
+	}
+	fmt.Printf("Closed %d timers.\n", len(tasks))
+	return nil
+}
+
+// recompute rebuilds the data derived from stored values. Reports and effort totals are
+// computed from the time entries on every request, so only the encrypted personal data
+// with its blind indexes, the other encrypted values and the planner statistics need
+// maintenance.
+func recompute(args []string) error {
+	flags := flag.NewFlagSet("recompute", flag.ExitOnError)
+	flags.Parse(args)
+
+	updated, err := database.ReencryptUsers()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-encrypted personal data and blind indexes of %d users.\n", updated)
+
+	updated, err = database.ReencryptSecrets()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-encrypted %d webhook secrets and stored responses.\n", updated)
+
+	if err := database.Analyze(); err != nil {
+		return err
+	}
+	fmt.Println("Refreshed planner statistics.")
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"os"
+	"strconv"
+	"time-tracker/internal/config"
+	"time-tracker/internal/database"
+)
+
+// migrateLogger prints the progress of golang-migrate to stderr.
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, v...)
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: admin migrate up|down|goto|force|version")
+	}
+	action, args := args[0], args[1:]
+
+	m, err := database.NewMigrate(cfg)
+	if err != nil {
+		return err
+	}
+	m.Log = migrateLogger{}
+
+	switch action {
+	case "up":
+		steps, err := optionalCount(args)
+		if err != nil {
+			return err
+		}
+		if steps == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(steps)
+		}
+		if err := checkMigration(err); err != nil {
+			return err
+		}
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		all := flags.Bool("all", false, "roll back every migration, dropping all data")
+		flags.Parse(args)
+		if *all {
+			err = m.Down()
+		} else {
+			steps, countErr := optionalCount(flags.Args())
+			if countErr != nil {
+				return countErr
+			}
+			if steps == 0 {
+				steps = 1
+			}
+			err = m.Steps(-steps)
+		}
+		if err := checkMigration(err); err != nil {
+			return err
+		}
+	case "goto":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		if version < 1 {
+			return errors.New("goto needs a version of 1 or higher, use down -all to roll back everything")
+		}
+		if err := checkMigration(m.Migrate(uint(version))); err != nil {
+			return err
+		}
+	case "force":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		if err := m.Force(version); err != nil {
+			return fmt.Errorf("failed to force version %d: %v", version, err)
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate command %q", action)
+	}
+
+	return printVersion(m)
+}
+
+// checkMigration treats "nothing to do" as success and explains how to recover from a
+// failed migration.
+func checkMigration(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("No change.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %v\nfix the cause, then mark the last good version with: admin migrate force V", err)
+	}
+	return nil
+}
+
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("Version: none (no migrations applied)")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the migration version: %v", err)
+	}
+	if dirty {
+		fmt.Printf("Version: %d (dirty: the last migration failed halfway)\n", version)
+		return nil
+	}
+	fmt.Printf("Version: %d\n", version)
+	return nil
+}
+
+func optionalCount(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid number of migrations: %s", args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unexpected arguments: %v", args[1:])
+	}
+}
+
+func versionArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("a version is required")
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < -1 {
+		return 0, fmt.Errorf("invalid version: %s", args[0])
+	}
+	return version, nil
+}
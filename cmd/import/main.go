@@ -0,0 +1,141 @@
+// Command import loads users or time entries from a CSV file, or time entries from a
+// Toggl Track, Clockify or Harvest export, straight into the database, the same way the
+// /import endpoints do. Run it from the repository root:
+//
+//	go run ./cmd/import -type users -file users.csv -dry-run
+//	go run ./cmd/import -type time-entries -file entries.csv -columns '{"start_time": "Начало"}'
+//	go run ./cmd/import -type toggl -file internal/importer/testdata/toggl.csv -timezone Europe/Moscow -dry-run
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time-tracker/internal/config"
+	"time-tracker/internal/database"
+	"time-tracker/internal/export"
+	"time-tracker/internal/importer"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	importType := flag.String("type", "", "what to import: users, time-entries, toggl, clockify or harvest")
+	path := flag.String("file", "", "CSV or JSON file to import")
+	columns := flag.String("columns", "", `column mapping as JSON, e.g. {"surname": "Фамилия"}`)
+	delimiter := flag.String("delimiter", "", `field delimiter: "," (default), ";" or "tab"`)
+	encoding := flag.String("encoding", "", "file encoding: utf-8 (default) or windows-1251")
+	dryRun := flag.Bool("dry-run", false, "validate and report without writing anything")
+	skipEnrichment := flag.Bool("skip-enrichment", false, "do not request user data from the external API")
+	users := flag.String("users", "", `tracker users mapped to user IDs as JSON, e.g. {"jane@example.com": 3}`)
+	timezone := flag.String("timezone", "", "zone of tracker exports without offsets, e.g. Europe/Moscow (default: local)")
+	dayStart := flag.String("day-start", "", "start of the working day for Harvest entries without times (default 09:00)")
+	actor := flag.String("actor", "cli", "actor recorded in the audit log")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	flag.Parse()
+
+	var runImport func(io.Reader, importer.CSVImportOptions) (models.BulkImportReport, error)
+	switch *importType {
+	case "users":
+		runImport = importer.ImportUsersCSV
+	case "time-entries":
+		runImport = importer.ImportTimeEntriesCSV
+	case importer.SourceToggl, importer.SourceClockify, importer.SourceHarvest:
+		trackerOptions := importer.TrackerImportOptions{DryRun: *dryRun, Actor: models.Actor{Name: *actor}}
+		var err error
+		if trackerOptions.Parse, err = importer.ParseTrackerOptions(*timezone, *dayStart); err != nil {
+			return err
+		}
+		if trackerOptions.Users, err = importer.ParseUserMapping(*users); err != nil {
+			return err
+		}
+		source := *importType
+		runImport = func(r io.Reader, _ importer.CSVImportOptions) (models.BulkImportReport, error) {
+			return importer.ImportTrackerExport(source, r, trackerOptions)
+		}
+	default:
+		flag.Usage()
+		return errors.New("-type must be users, time-entries, toggl, clockify or harvest")
+	}
+	if *path == "" {
+		flag.Usage()
+		return errors.New("-file is required")
+	}
+
+	options := importer.CSVImportOptions{DryRun: *dryRun, SkipEnrichment: *skipEnrichment, Actor: models.Actor{Name: *actor}}
+	var err error
+	if options.Dialect, err = export.ParseCSVOptions(*delimiter, *encoding); err != nil {
+		return err
+	}
+	if options.Columns, err = importer.ParseColumnMapping(*columns); err != nil {
+		return err
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	logger.InitLogger()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := pii.Init(cfg); err != nil {
+		return err
+	}
+	if err := database.InitDB(cfg); err != nil {
+		return err
+	}
+
+	report, err := runImport(file, options)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printReport(report)
+	return nil
+}
+
+// printReport prints the totals and the rows that were not imported as new.
+func printReport(report models.BulkImportReport) {
+	if report.DryRun {
+		fmt.Println("Dry run, nothing was written.")
+	}
+	fmt.Printf("Rows: %d, new: %d, imported: %d, already present: %d, invalid: %d\n",
+		report.Total, report.New, report.Imported, report.Exists, report.Invalid)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := false
+	for _, row := range report.Rows {
+		if row.Status != models.ImportStatusInvalid {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "\nROW\tCOLUMN\tERROR")
+			header = true
+		}
+		for _, fieldErr := range row.Errors {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", row.Row, fieldErr.Field, fieldErr.Message)
+		}
+	}
+	tw.Flush()
+}
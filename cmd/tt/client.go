@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the version of the server API the client speaks.
+const apiPrefix = "/api/v1"
+
+type client struct {
+	config cliConfig
+	http   *http.Client
+}
+
+func newClient(config cliConfig) *client {
+	return &client{config: config, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// apiError is a response outside 2xx. The server answers errors with plain text.
+type apiError struct {
+	Status  string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return "server responded " + e.Status
+	}
+	return fmt.Sprintf("server responded %s: %s", e.Status, e.Message)
+}
+
+// do sends the request and returns the response body. A non-nil body is sent as JSON.
+func (c *client) do(method, path string, query url.Values, body interface{}) ([]byte, error) {
+	return c.doWithHeader(method, path, query, body, nil)
+}
+
+// doWithHeader is do with additional request headers, such as If-Match.
+func (c *client) doWithHeader(method, path string, query url.Values, body interface{}, header http.Header) ([]byte, error) {
+	endpoint := strings.TrimRight(c.config.URL, "/") + apiPrefix + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	if c.config.Actor != "" {
+		req.Header.Set("X-Actor", c.config.Actor)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &apiError{Status: resp.Status, Message: strings.TrimSpace(string(data))}
+	}
+	return data, nil
+}
+
+func (c *client) getJSON(path string, query url.Values, v interface{}) error {
+	data, err := c.do(http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unexpected response from %s: %v", path, err)
+	}
+	return nil
+}
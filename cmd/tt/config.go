@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cliConfig is stored as JSON in the user's config directory. It holds a token, so
+// the file is readable by its owner only.
+type cliConfig struct {
+	URL    string `json:"url"`
+	Token  string `json:"token,omitempty"`
+	UserID int    `json:"user_id,omitempty"`
+	Actor  string `json:"actor,omitempty"`
+}
+
+const defaultURL = "http://localhost:8080"
+
+// configPath returns $TT_CONFIG, or tt/config.json in the user's config directory.
+func configPath() (string, error) {
+	if path := os.Getenv("TT_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tt", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file is not an error. TT_URL and TT_TOKEN
+// override the stored values.
+func loadConfig() (cliConfig, error) {
+	config := cliConfig{URL: defaultURL}
+	path, err := configPath()
+	if err != nil {
+		return config, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return config, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+
+	if url := os.Getenv("TT_URL"); url != "" {
+		config.URL = url
+	}
+	if token := os.Getenv("TT_TOKEN"); token != "" {
+		config.Token = token
+	}
+	return config, nil
+}
+
+func saveConfig(config cliConfig) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o600)
+}
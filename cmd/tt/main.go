@@ -0,0 +1,566 @@
+// Command tt is a terminal client for the time tracker REST API. The server URL, an
+// access token and a default user are kept in a config file:
+//
+//	go run ./cmd/tt config -url http://localhost:8080 -token secret -user 7
+//	go run ./cmd/tt start Code review
+//	go run ./cmd/tt status
+//	go run ./cmd/tt stop
+//	go run ./cmd/tt log -week
+//	go run ./cmd/tt report -month 2024-05 -format pdf -o may.pdf
+//
+// Every command that prints data accepts -json.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"time-tracker/internal/models"
+)
+
+const usage = `Usage: tt <command> [flags] [arguments]
+
+Commands:
+  config     show or change the server URL, token, default user and actor
+  start      start a timer: tt start [-user N] [-d description] title...
+  stop       stop a timer: tt stop [task ID], the running timer of the user by default
+  status     show running timers
+  log        list time entries: tt log [-week | -from YYYY-MM-DD -to YYYY-MM-DD]
+  add-user   add a user by passport number: tt add-user "1234 567890"
+  report     daily totals, or a CSV/XLSX worklog or PDF timesheet with -format
+
+Run tt <command> -h for the flags of a command.
+`
+
+type command func(c *client, args []string) error
+
+var commands = map[string]command{
+	"start":    runStart,
+	"stop":     runStop,
+	"status":   runStatus,
+	"log":      runLog,
+	"add-user": runAddUser,
+	"report":   runReport,
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "tt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if args[0] == "config" {
+		return runConfig(config, args[1:])
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd(newClient(config), args[1:])
+}
+
+func runConfig(config cliConfig, args []string) error {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	serverURL := flags.String("url", "", "server URL, e.g. http://localhost:8080")
+	token := flags.String("token", "", "access token sent as a bearer token")
+	userID := flags.Int("user", 0, "default user ID for start, stop, status, log and report")
+	actor := flags.String("actor", "", "name sent as X-Actor, recorded as the claimed actor in the audit log")
+	flags.Parse(args)
+
+	changed := false
+	flags.Visit(func(f *flag.Flag) { changed = true })
+	if changed {
+		if *serverURL != "" {
+			if _, err := url.ParseRequestURI(*serverURL); err != nil {
+				return fmt.Errorf("invalid -url: %v", err)
+			}
+			config.URL = *serverURL
+		}
+		if *token != "" {
+			config.Token = *token
+		}
+		if *userID != 0 {
+			config.UserID = *userID
+		}
+		if *actor != "" {
+			config.Actor = *actor
+		}
+		path, err := saveConfig(config)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Saved", path)
+	}
+
+	tokenState := "(not set)"
+	if config.Token != "" {
+		tokenState = "(set)"
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "url\t%s\n", config.URL)
+	fmt.Fprintf(tw, "token\t%s\n", tokenState)
+	fmt.Fprintf(tw, "user\t%s\n", optionalInt(config.UserID))
+	fmt.Fprintf(tw, "actor\t%s\n", config.Actor)
+	return tw.Flush()
+}
+
+func runStart(c *client, args []string) error {
+	flags := flag.NewFlagSet("start", flag.ExitOnError)
+	userID := flags.Int("user", 0, "user ID (default from config)")
+	description := flags.String("d", "", "task description")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	user, err := c.userID(*userID)
+	if err != nil {
+		return err
+	}
+	title := strings.Join(flags.Args(), " ")
+	if title == "" {
+		return errors.New("a task title is required: tt start <title>")
+	}
+
+	body := models.TaskRequest{Title: title, Description: *description}
+	data, err := c.do(http.MethodPost, fmt.Sprintf("/users/%d/time-entries", user), nil, body)
+	if err != nil {
+		return err
+	}
+	var task models.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return fmt.Errorf("unexpected response: %s", data)
+	}
+
+	if *asJSON {
+		return printJSON(task)
+	}
+	fmt.Printf("Started timer %d for user %d: %s\n", task.TaskID, user, title)
+	return nil
+}
+
+func runStop(c *client, args []string) error {
+	flags := flag.NewFlagSet("stop", flag.ExitOnError)
+	userID := flags.Int("user", 0, "user whose running timer is stopped (default from config)")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	var timer *models.TimerEvent
+	var taskID int
+	switch flags.NArg() {
+	case 0:
+		user, err := c.userID(*userID)
+		if err != nil {
+			return err
+		}
+		timers, err := c.runningTimers(models.TimerFilter{UserID: user})
+		if err != nil {
+			return err
+		}
+		switch len(timers) {
+		case 0:
+			return fmt.Errorf("user %d has no running timer", user)
+		case 1:
+			timer = &timers[0]
+			taskID = timer.TaskID
+		default:
+			ids := make([]string, 0, len(timers))
+			for _, t := range timers {
+				ids = append(ids, strconv.Itoa(t.TaskID))
+			}
+			return fmt.Errorf("user %d has %d running timers (%s), pass the task ID", user, len(timers), strings.Join(ids, ", "))
+		}
+	case 1:
+		id, err := strconv.Atoi(flags.Arg(0))
+		if err != nil || id < 1 {
+			return fmt.Errorf("invalid task ID: %s", flags.Arg(0))
+		}
+		taskID = id
+	default:
+		return errors.New("usage: tt stop [task ID]")
+	}
+
+	// The server stops a timer only for the version it was read at.
+	var task models.Task
+	if err := c.getJSON(fmt.Sprintf("/time-entries/%d", taskID), nil, &task); err != nil {
+		return err
+	}
+	header := http.Header{"If-Match": {fmt.Sprintf(`"%d"`, task.Version)}}
+	if _, err := c.doWithHeader(http.MethodPost, fmt.Sprintf("/time-entries/%d/stop", taskID), nil, nil, header); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		result := map[string]interface{}{"task_id": taskID}
+		if timer != nil {
+			result["title"] = timer.Title
+			result["elapsed_seconds"] = int64(time.Since(timer.StartTime).Seconds())
+		}
+		return printJSON(result)
+	}
+	if timer != nil {
+		fmt.Printf("Stopped timer %d (%s) after %s\n", taskID, timer.Title, formatElapsed(time.Since(timer.StartTime)))
+		return nil
+	}
+	fmt.Printf("Stopped timer %d\n", taskID)
+	return nil
+}
+
+func runStatus(c *client, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	userID := flags.Int("user", 0, "user ID (default from config)")
+	team := flags.String("team", "", "show the running timers of a team")
+	all := flags.Bool("all", false, "show the running timers of everyone")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	filter := models.TimerFilter{Team: *team}
+	if !*all && *team == "" {
+		filter.UserID = *userID
+		if filter.UserID == 0 {
+			filter.UserID = c.config.UserID
+		}
+	}
+	timers, err := c.runningTimers(filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(timers)
+	}
+	if len(timers) == 0 {
+		fmt.Println("No running timers.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tUSER\tTEAM\tTITLE\tSTARTED\tELAPSED")
+	for _, t := range timers {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\n", t.TaskID, t.UserID, t.Team, t.Title,
+			t.StartTime.Local().Format("2006-01-02 15:04"), formatElapsed(time.Duration(t.ElapsedSeconds)*time.Second))
+	}
+	return tw.Flush()
+}
+
+func runLog(c *client, args []string) error {
+	flags := flag.NewFlagSet("log", flag.ExitOnError)
+	userID := flags.Int("user", 0, "user ID (default from config)")
+	var period periodFlags
+	period.register(flags, false)
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	user, err := c.userID(*userID)
+	if err != nil {
+		return err
+	}
+	start, end, err := period.resolve(time.Now())
+	if err != nil {
+		return err
+	}
+	worklog, err := c.worklog(user, start, end)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(worklog)
+	}
+	fmt.Printf("User %d, %s – %s\n", user, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if len(worklog.Entries) == 0 {
+		fmt.Println("No time entries.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tSTART\tEND\tDURATION\tTASK\tTITLE")
+	for _, task := range worklog.Entries {
+		start, end := task.StartTime.Local(), task.EndTime.Local()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", start.Format("Mon 02.01"), start.Format("15:04"), end.Format("15:04"),
+			formatElapsed(end.Sub(start)), task.TaskID, task.Title)
+	}
+	fmt.Fprintf(tw, "\t\tTotal\t%s\t\t\n", formatElapsed(time.Duration(worklog.TotalSeconds)*time.Second))
+	return tw.Flush()
+}
+
+func runAddUser(c *client, args []string) error {
+	flags := flag.NewFlagSet("add-user", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	passport := strings.Join(flags.Args(), " ")
+	if passport == "" {
+		return errors.New(`a passport number is required: tt add-user "1234 567890"`)
+	}
+
+	data, err := c.do(http.MethodPost, "/users", nil, models.UserRequest{PassportNumber: passport})
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && *asJSON && json.Valid([]byte(apiErr.Message)) {
+			// Validation errors come as JSON; pass them through for scripts.
+			fmt.Println(apiErr.Message)
+		}
+		return err
+	}
+
+	var user models.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return fmt.Errorf("unexpected response: %s", data)
+	}
+	if *asJSON {
+		return printJSON(user)
+	}
+	fmt.Printf("Added user %d: %s\n", user.ID, strings.TrimSpace(user.Surname+" "+user.Name+" "+user.Patronymic))
+	return nil
+}
+
+// dailyTotal is a row of the daily report.
+type dailyTotal struct {
+	Date    string `json:"date"`
+	Entries int    `json:"entries"`
+	Seconds int64  `json:"seconds"`
+}
+
+func runReport(c *client, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	userID := flags.Int("user", 0, "user ID (default from config)")
+	var period periodFlags
+	period.register(flags, true)
+	format := flags.String("format", "table", "table, json, csv, xlsx or pdf (a monthly timesheet, needs -month)")
+	output := flags.String("o", "", "file for csv, xlsx and pdf reports (default stdout)")
+	asJSON := flags.Bool("json", false, "print JSON, the same as -format json")
+	flags.Parse(args)
+	if *asJSON {
+		*format = "json"
+	}
+
+	user, err := c.userID(*userID)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "table", "json":
+	case "pdf":
+		if period.month == "" {
+			return errors.New("-format pdf needs -month YYYY-MM")
+		}
+		data, err := c.do(http.MethodGet, fmt.Sprintf("/users/%d/timesheet", user), url.Values{"month": {period.month}}, nil)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*output, data)
+	case "csv", "xlsx":
+		start, end, err := period.resolve(time.Now())
+		if err != nil {
+			return err
+		}
+		query := periodQuery(start, end)
+		query.Set("format", *format)
+		data, err := c.do(http.MethodGet, fmt.Sprintf("/users/%d/worklog", user), query, nil)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*output, data)
+	default:
+		return fmt.Errorf("unsupported format: %s", *format)
+	}
+
+	start, end, err := period.resolve(time.Now())
+	if err != nil {
+		return err
+	}
+	worklog, err := c.worklog(user, start, end)
+	if err != nil {
+		return err
+	}
+
+	byDate := map[string]*dailyTotal{}
+	for _, task := range worklog.Entries {
+		date := task.StartTime.Local().Format("2006-01-02")
+		if byDate[date] == nil {
+			byDate[date] = &dailyTotal{Date: date}
+		}
+		byDate[date].Entries++
+		byDate[date].Seconds += int64(task.EndTime.Sub(task.StartTime).Seconds())
+	}
+	totals := make([]dailyTotal, 0, len(byDate))
+	for _, total := range byDate {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Date < totals[j].Date })
+
+	if *format == "json" {
+		return printJSON(map[string]interface{}{
+			"user_id":       user,
+			"period_start":  start,
+			"period_end":    end,
+			"days":          totals,
+			"total_seconds": worklog.TotalSeconds,
+		})
+	}
+
+	fmt.Printf("User %d, %s – %s\n", user, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tDAY\tENTRIES\tHOURS")
+	entries := 0
+	for _, total := range totals {
+		date, _ := time.ParseInLocation("2006-01-02", total.Date, time.Local)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", total.Date, date.Format("Mon"), total.Entries,
+			formatElapsed(time.Duration(total.Seconds)*time.Second))
+		entries += total.Entries
+	}
+	fmt.Fprintf(tw, "Total\t\t%d\t%s\n", entries, formatElapsed(time.Duration(worklog.TotalSeconds)*time.Second))
+	return tw.Flush()
+}
+
+// userID returns the user given on the command line, or the default one from the config.
+func (c *client) userID(flagValue int) (int, error) {
+	if flagValue != 0 {
+		return flagValue, nil
+	}
+	if c.config.UserID != 0 {
+		return c.config.UserID, nil
+	}
+	return 0, errors.New("no user: pass -user or set a default with tt config -user N")
+}
+
+func (c *client) runningTimers(filter models.TimerFilter) ([]models.TimerEvent, error) {
+	query := url.Values{}
+	if filter.UserID != 0 {
+		query.Set("userId", strconv.Itoa(filter.UserID))
+	}
+	if filter.Team != "" {
+		query.Set("team", filter.Team)
+	}
+	var timers []models.TimerEvent
+	err := c.getJSON("/timers", query, &timers)
+	return timers, err
+}
+
+func (c *client) worklog(userID int, start, end time.Time) (models.Worklog, error) {
+	query := periodQuery(start, end)
+	query.Set("format", "json")
+	var worklog models.Worklog
+	err := c.getJSON(fmt.Sprintf("/users/%d/worklog", userID), query, &worklog)
+	return worklog, err
+}
+
+func periodQuery(start, end time.Time) url.Values {
+	return url.Values{"startPeriod": {start.Format(time.RFC3339)}, "endPeriod": {end.Format(time.RFC3339)}}
+}
+
+// periodFlags selects the reported period: today by default, the current week, a month
+// or a range of dates.
+type periodFlags struct {
+	week     bool
+	from, to string
+	month    string
+}
+
+func (p *periodFlags) register(flags *flag.FlagSet, month bool) {
+	flags.BoolVar(&p.week, "week", false, "the current week, Monday to Sunday")
+	flags.StringVar(&p.from, "from", "", "first day, YYYY-MM-DD")
+	flags.StringVar(&p.to, "to", "", "last day, YYYY-MM-DD (default today)")
+	if month {
+		flags.StringVar(&p.month, "month", "", "a calendar month, YYYY-MM")
+	}
+}
+
+// resolve returns the first and last second of the period in local time.
+func (p *periodFlags) resolve(now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	var start, next time.Time
+	switch {
+	case p.month != "":
+		month, err := time.ParseInLocation("2006-01", p.month, time.Local)
+		if err != nil {
+			return start, next, fmt.Errorf("invalid -month %q, expected YYYY-MM", p.month)
+		}
+		start, next = month, month.AddDate(0, 1, 0)
+	case p.week:
+		offset := (int(today.Weekday()) + 6) % 7 // days since Monday
+		start = today.AddDate(0, 0, -offset)
+		next = start.AddDate(0, 0, 7)
+	case p.from != "":
+		var err error
+		if start, err = time.ParseInLocation("2006-01-02", p.from, time.Local); err != nil {
+			return start, next, fmt.Errorf("invalid -from %q, expected YYYY-MM-DD", p.from)
+		}
+		next = today.AddDate(0, 0, 1)
+		if p.to != "" {
+			to, err := time.ParseInLocation("2006-01-02", p.to, time.Local)
+			if err != nil {
+				return start, next, fmt.Errorf("invalid -to %q, expected YYYY-MM-DD", p.to)
+			}
+			next = to.AddDate(0, 0, 1)
+		}
+		if !next.After(start) {
+			return start, next, errors.New("-to is before -from")
+		}
+	default:
+		start, next = today, today.AddDate(0, 0, 1)
+	}
+	return start, next.Add(-time.Second), nil
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeOutput(path string, data []byte) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Saved %s (%d bytes)\n", path, len(data))
+	}
+	return nil
+}
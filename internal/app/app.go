@@ -1,13 +1,20 @@
 package app
 
 import (
+	"context"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
 	"net/http"
 	"time-tracker/internal/config"
 	"time-tracker/internal/database"
+	"time-tracker/internal/docs"
+	"time-tracker/internal/enrichment"
+	"time-tracker/internal/grpcapi"
 	"time-tracker/internal/handlers"
 	"time-tracker/internal/logger"
+	"time-tracker/internal/pii"
+	"time-tracker/internal/service"
+	"time-tracker/internal/webhooks"
 )
 
 func Run() error {
@@ -19,28 +26,59 @@ func Run() error {
 		return err
 	}
 
+	err = pii.Init(cfg)
+	if err != nil {
+		return err
+	}
+
 	err = database.InitDB(cfg)
 	if err != nil {
 		return err
 	}
 
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	reencrypted, err := database.ReencryptUsers()
+	if err != nil {
+		return err
+	}
+	logger.Logger.Info("Personal data encrypted with the current key", zap.Int("updatedUsers", reencrypted))
 
-	r.Route("/users", func(r chi.Router) {
+	reencrypted, err = database.ReencryptSecrets()
+	if err != nil {
+		return err
+	}
+	logger.Logger.Info("Secrets encrypted with the current key", zap.Int("updatedRows", reencrypted))
 
-		r.Get("/", handlers.GetUsers)
-		r.Get("/{id}/worklog", handlers.GetWorkLog)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.Run(ctx, cfg.WebhookRetention)
+	go service.RelayTimerEvents(ctx)
 
-		r.Post("/add", handlers.AddUser)
-		r.Post("/{id}/task/start", handlers.StartTask)
-		r.Post("/task/{id}/stop", handlers.StopTask)
+	idempotencyWindow = cfg.IdempotencyWindow
+	go purgeIdempotencyKeys(ctx)
 
-		r.Delete("/{id}", handlers.DeleteUser)
+	limits = newRateLimits(cfg)
+	enrichment.SetRateLimit(cfg.EnrichmentAPIRateLimit)
+	grpcapi.SetRateLimits(cfg)
+	handlers.SetAllowedOrigins(cfg.AllowedOrigins)
 
-		r.Put("/{id}", handlers.UpdateUser)
+	if cfg.GRPCAddr != "" {
+		err = grpcapi.Serve(ctx, cfg.GRPCAddr)
+		if err != nil {
+			return err
+		}
+	}
 
-	})
+	r := NewRouter()
+
+	// Routes missing from the OpenAPI spec are reported, not fatal: admin docs check
+	// fails on them before a release.
+	undocumented, err := docs.Undocumented(r)
+	if err != nil {
+		return err
+	}
+	for _, route := range undocumented {
+		logger.Logger.Warn("Route is not documented in the OpenAPI spec", zap.String("route", route))
+	}
 
 	err = http.ListenAndServe("localhost:8080", r)
 	if err != nil {
@@ -48,3 +86,108 @@ func Run() error {
 	}
 	return nil
 }
+
+// NewRouter registers every route of the API. It needs no database connection, so the
+// routes can be inspected without starting the server.
+func NewRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requestLogger())
+	r.Use(rateLimit(r))
+	r.Use(idempotent)
+
+	r.Route(handlers.APIPrefix, func(r chi.Router) {
+		r.Route("/users", func(r chi.Router) {
+			r.With(conditionalGet).Get("/", handlers.ListUsers)
+			r.With(conditionalGet).Get("/{id}", handlers.GetUser)
+			r.With(conditionalGet).Get("/{id}/time-entries", handlers.ListTimeEntries)
+
+			r.With(enrichmentQuota).Post("/", handlers.CreateUser)
+			r.Post("/{id}/time-entries", handlers.StartTimeEntry)
+
+			r.Put("/{id}", handlers.PatchUser)
+			r.Patch("/{id}", handlers.PatchUser)
+
+			r.Delete("/{id}", handlers.RemoveUser)
+
+			userRoutes(r)
+		})
+
+		r.Route("/time-entries", func(r chi.Router) {
+			r.With(conditionalGet).Get("/{id}", handlers.GetTimeEntry)
+			r.Post("/{id}/stop", handlers.StopTimeEntry)
+		})
+
+		r.Post("/graphql", handlers.GraphQL)
+
+		sharedRoutes(r)
+	})
+
+	// The routes from before /api/v1, kept for existing clients.
+	r.Group(func(r chi.Router) {
+		r.Use(deprecated)
+
+		r.Route("/users", func(r chi.Router) {
+
+			r.With(conditionalGet).Get("/", handlers.GetUsers)
+
+			r.With(enrichmentQuota).Post("/add", handlers.AddUser)
+			r.Post("/{id}/task/start", handlers.StartTask)
+			r.Post("/task/{id}/stop", handlers.StopTask)
+
+			r.Delete("/{id}", handlers.DeleteUser)
+
+			r.Put("/{id}", handlers.UpdateUser)
+
+			userRoutes(r)
+		})
+
+		sharedRoutes(r)
+	})
+
+	r.Get("/openapi.json", handlers.GetOpenAPISpec)
+	r.Get("/docs", handlers.GetDocs)
+
+	return r
+}
+
+// userRoutes registers the routes under /users that are the same in both API versions.
+func userRoutes(r chi.Router) {
+	r.With(conditionalGet).Get("/{id}/worklog", handlers.GetWorkLog)
+	r.Get("/{id}/export", handlers.ExportUserData)
+	r.With(conditionalGet).Get("/{id}/timesheet", handlers.GetTimesheet)
+	r.Get("/{id}/calendar.ics", handlers.GetCalendarFeed)
+
+	r.Post("/{id}/anonymize", handlers.AnonymizeUser)
+	r.Post("/{id}/calendar/token", handlers.CreateCalendarToken)
+	r.Post("/{id}/calendar/import", handlers.ImportCalendar)
+
+	r.Delete("/{id}/calendar/token", handlers.RevokeCalendarToken)
+}
+
+// sharedRoutes registers the routes that are the same in both API versions.
+func sharedRoutes(r chi.Router) {
+	r.With(conditionalGet).Get("/audit", handlers.GetAuditLog)
+	r.With(conditionalGet).Get("/search", handlers.Search)
+	r.With(conditionalGet).Get("/timesheets", handlers.GetTeamTimesheets)
+	r.With(conditionalGet).Get("/timers", handlers.GetRunningTimers)
+	r.Get("/timers/stream", handlers.StreamTimers)
+
+	r.Route("/import", func(r chi.Router) {
+		r.With(importEnrichmentQuota).Post("/users", handlers.ImportUsers)
+		r.Post("/time-entries", handlers.ImportTimeEntries)
+		r.Post("/{source:toggl|clockify|harvest}", handlers.ImportTrackerExport)
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Get("/", handlers.GetWebhooks)
+		r.Get("/{id}", handlers.GetWebhook)
+		r.Get("/{id}/deliveries", handlers.GetWebhookDeliveries)
+
+		r.Post("/", handlers.CreateWebhook)
+		r.Post("/{id}/deliveries/{deliveryId}/retry", handlers.RetryWebhookDelivery)
+
+		r.Put("/{id}", handlers.UpdateWebhook)
+
+		r.Delete("/{id}", handlers.DeleteWebhook)
+	})
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// conditionalGet supports If-None-Match on lists and reports. Successful responses are
+// tagged with a hash of the body, unless the handler set an ETag itself, and a client
+// that already has the same body gets 304 Not Modified without it. The response is
+// still built, so this saves the transfer rather than the queries.
+func conditionalGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buffered := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+
+		if buffered.status == http.StatusOK {
+			tag := w.Header().Get("ETag")
+			if tag == "" {
+				sum := sha256.Sum256(buffered.body.Bytes())
+				tag = fmt.Sprintf(`"%x"`, sum[:16])
+				w.Header().Set("ETag", tag)
+			}
+			if noneMatch(r.Header.Get("If-None-Match"), tag) {
+				w.Header().Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(buffered.status)
+		w.Write(buffered.body.Bytes())
+	})
+}
+
+// noneMatch tells whether an If-None-Match header lists the tag. The comparison is
+// weak, as RFC 9110 requires for If-None-Match.
+func noneMatch(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse holds back the status and body of a response; headers go straight
+// to the underlying writer.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// legacyDeprecatedSince is when the routes outside /api/v1 were deprecated.
+var legacyDeprecatedSince = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks responses of the legacy routes with the Deprecation header (RFC 9745)
+// and links to the documentation of their replacements.
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecatedSince.Unix()))
+		w.Header().Add("Link", `</docs>; rel="deprecation"; type="text/html"`)
+		next.ServeHTTP(w, r)
+	})
+}
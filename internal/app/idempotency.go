@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize matches the largest upload the import handlers accept.
+	maxIdempotentBodySize = 10 << 20
+
+	idempotencyPurgeInterval = time.Hour
+)
+
+// idempotencyWindow is how long responses are kept for retries; Run sets it from the
+// config.
+var idempotencyWindow = 24 * time.Hour
+
+// idempotencyStore keeps the requests made with each Idempotency-Key and their
+// responses.
+type idempotencyStore interface {
+	Reserve(client, key, fingerprint string, expiredBefore time.Time) (database.IdempotentRequest, bool, error)
+	Complete(client, key string, status int, header http.Header, body []byte) error
+	Release(client, key string) error
+}
+
+// databaseIdempotencyStore keeps the keys in PostgreSQL, shared by all instances of
+// the server.
+type databaseIdempotencyStore struct{}
+
+func (databaseIdempotencyStore) Reserve(client, key, fingerprint string, expiredBefore time.Time) (database.IdempotentRequest, bool, error) {
+	return database.ReserveIdempotencyKey(client, key, fingerprint, expiredBefore)
+}
+
+func (databaseIdempotencyStore) Complete(client, key string, status int, header http.Header, body []byte) error {
+	return database.CompleteIdempotencyKey(client, key, status, header, body)
+}
+
+func (databaseIdempotencyStore) Release(client, key string) error {
+	return database.ReleaseIdempotencyKey(client, key)
+}
+
+var idempotencyKeys idempotencyStore = databaseIdempotencyStore{}
+
+// idempotent lets clients retry a POST, PUT, PATCH or DELETE safely by sending the same
+// Idempotency-Key header. Keys are scoped by client. The first request with a key is
+// processed and its response stored; a retry with the same method, URL and body gets
+// that response back, marked with Idempotent-Replayed. Reusing a key for a different
+// request is answered with 422 and a retry that arrives while the first request is
+// still running with 409. Requests that fail with a server error are not stored, so
+// they can be retried.
+func idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+		client := idempotencyClient(r)
+
+		stored, reserved, err := idempotencyKeys.Reserve(client, key, fingerprint, time.Now().Add(-idempotencyWindow))
+		if err != nil {
+			logger.Logger.Error("Failed to reserve idempotency key", zap.Error(err))
+			http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			switch {
+			case stored.Fingerprint != fingerprint:
+				logger.Logger.Warn("Idempotency key reused for a different request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+				http.Error(w, "Idempotency-Key has already been used for a different request", http.StatusUnprocessableEntity)
+			case !stored.Completed:
+				http.Error(w, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+			default:
+				replay(w, stored)
+			}
+			return
+		}
+
+		buffered := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+
+		if buffered.status >= http.StatusInternalServerError {
+			err = idempotencyKeys.Release(client, key)
+		} else {
+			err = idempotencyKeys.Complete(client, key, buffered.status, w.Header(), buffered.body.Bytes())
+		}
+		if err != nil {
+			logger.Logger.Error("Failed to store idempotent response", zap.Error(err))
+		}
+		w.WriteHeader(buffered.status)
+		w.Write(buffered.body.Bytes())
+	})
+}
+
+// idempotencyClient tells apart the clients whose keys are kept separately: by a hash
+// of the Authorization header, or else by IP address. The API does not verify tokens,
+// but a token is known only to its client, so another client cannot reach its stored
+// responses; unlike with rate limits, there is nothing to gain by changing it.
+func idempotencyClient(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		return "auth:" + hex.EncodeToString(sum[:])
+	}
+	return clientKey(r)
+}
+
+// requestFingerprint identifies a request by its method, URL and body, so a key cannot
+// replay the response to another request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, stored database.IdempotentRequest) {
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// purgeIdempotencyKeys deletes expired keys until the context is cancelled.
+func purgeIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := database.PurgeIdempotencyKeys(time.Now().Add(-idempotencyWindow))
+		if err != nil {
+			logger.Logger.Error("Failed to purge idempotency keys", zap.Error(err))
+		} else if purged > 0 {
+			logger.Logger.Info("Purged expired idempotency keys", zap.Int64("keys", purged))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+)
+
+// memoryIdempotencyStore keeps keys the way the idempotency_keys table does.
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]database.IdempotentRequest
+	reserved map[string]time.Time
+}
+
+func useMemoryIdempotencyStore(t *testing.T) *memoryIdempotencyStore {
+	t.Helper()
+	logger.Logger = zap.NewNop()
+	store := &memoryIdempotencyStore{
+		requests: make(map[string]database.IdempotentRequest),
+		reserved: make(map[string]time.Time),
+	}
+	previous := idempotencyKeys
+	idempotencyKeys = store
+	t.Cleanup(func() { idempotencyKeys = previous })
+	return store
+}
+
+func (s *memoryIdempotencyStore) Reserve(client, key, fingerprint string, expiredBefore time.Time) (database.IdempotentRequest, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := client + " " + key
+	if stored, ok := s.requests[id]; ok && !s.reserved[id].Before(expiredBefore) {
+		return stored, false, nil
+	}
+	s.requests[id] = database.IdempotentRequest{Fingerprint: fingerprint}
+	s.reserved[id] = time.Now()
+	return database.IdempotentRequest{}, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(client, key string, status int, header http.Header, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := client + " " + key
+	stored := s.requests[id]
+	stored.Completed = true
+	stored.Status = status
+	stored.Header = header.Clone()
+	stored.Body = append([]byte(nil), body...)
+	s.requests[id] = stored
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(client, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := client + " " + key
+	if !s.requests[id].Completed {
+		delete(s.requests, id)
+		delete(s.reserved, id)
+	}
+	return nil
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users/1/time-entries", strings.NewReader(body))
+	r.Header.Set("Idempotency-Key", key)
+	return r
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/api/v1/time-entries/7")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"task_id":7}`))
+	}))
+
+	first := serve(handler, idempotentRequest("key-1", `{"title":"Review"}`))
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: status %d, headers %v", first.Code, first.Header())
+	}
+
+	retry := serve(handler, idempotentRequest("key-1", `{"title":"Review"}`))
+	if calls != 1 {
+		t.Errorf("handler called %d times, want once", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"task_id":7}` ||
+		retry.Header().Get("Location") != "/api/v1/time-entries/7" || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry: status %d, body %q, headers %v, want the stored response", retry.Code, retry.Body, retry.Header())
+	}
+
+	// Keys are kept per client, so another token gets its own request processed.
+	other := idempotentRequest("key-1", `{"title":"Review"}`)
+	other.Header.Set("Authorization", "Bearer another")
+	if w := serve(handler, other); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" || calls != 2 {
+		t.Errorf("another client: status %d, replayed %q, %d calls", w.Code, w.Header().Get("Idempotent-Replayed"), calls)
+	}
+
+	// Requests without a key, and reads, are never stored.
+	unkeyed := httptest.NewRequest(http.MethodPost, "/api/v1/users/1/time-entries", strings.NewReader(`{}`))
+	serve(handler, unkeyed)
+	read := idempotentRequest("key-1", "")
+	read.Method = http.MethodGet
+	serve(handler, read)
+	if calls != 4 {
+		t.Errorf("handler called %d times, want requests without a key or with GET passed through", calls)
+	}
+}
+
+func TestIdempotentFingerprintMismatch(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve(handler, idempotentRequest("key-1", `{"title":"Review"}`))
+	tests := map[string]*http.Request{
+		"body":   idempotentRequest("key-1", `{"title":"Deploy"}`),
+		"path":   httptest.NewRequest(http.MethodPost, "/api/v1/users/2/time-entries", strings.NewReader(`{"title":"Review"}`)),
+		"method": httptest.NewRequest(http.MethodPut, "/api/v1/users/1/time-entries", strings.NewReader(`{"title":"Review"}`)),
+	}
+	for name, r := range tests {
+		r.Header.Set("Idempotency-Key", "key-1")
+		if w := serve(handler, r); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("different %s: status %d, want 422", name, w.Code)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want once", calls)
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(handler, idempotentRequest("key-1", `{}`)) }()
+	<-entered
+
+	if w := serve(handler, idempotentRequest("key-1", `{}`)); w.Code != http.StatusConflict {
+		t.Errorf("retry while the first request runs: status %d, want 409", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("first request: status %d, want 201", w.Code)
+	}
+	if w := serve(handler, idempotentRequest("key-1", `{}`)); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after the first request: status %d, want the stored 201", w.Code)
+	}
+}
+
+func TestIdempotentReleaseAfterServerError(t *testing.T) {
+	store := useMemoryIdempotencyStore(t)
+	statuses := []int{http.StatusServiceUnavailable, http.StatusCreated}
+	calls := 0
+	handler := idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls])
+		calls++
+	}))
+
+	if w := serve(handler, idempotentRequest("key-1", `{}`)); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first request: status %d, want 503", w.Code)
+	}
+	if len(store.requests) != 0 {
+		t.Errorf("the key is still stored after a server error: %v", store.requests)
+	}
+
+	w := serve(handler, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" || calls != 2 {
+		t.Errorf("retry: status %d, replayed %q, %d calls, want the request processed again", w.Code, w.Header().Get("Idempotent-Replayed"), calls)
+	}
+
+	// Client errors are final, so they are stored and replayed like successes.
+	rejecting := idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	}))
+	serve(rejecting, idempotentRequest("key-2", `{`))
+	if w := serve(rejecting, idempotentRequest("key-2", `{`)); w.Code != http.StatusBadRequest || w.Header().Get("Idempotent-Replayed") != "true" || calls != 3 {
+		t.Errorf("retry of a rejected request: status %d, replayed %q, %d calls", w.Code, w.Header().Get("Idempotent-Replayed"), calls)
+	}
+}
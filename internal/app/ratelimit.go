@@ -0,0 +1,130 @@
+package app
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time-tracker/internal/config"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/ratelimit"
+)
+
+// rateLimits are the limits the middlewares enforce. They are unlimited until Run sets
+// them from the config.
+type rateLimits struct {
+	client      *ratelimit.Limiter
+	write       *ratelimit.Limiter
+	enrichment  *ratelimit.Limiter
+	maxBodySize int64
+}
+
+func newRateLimits(cfg *config.Config) *rateLimits {
+	return &rateLimits{
+		client:      ratelimit.New(cfg.RateLimit),
+		write:       ratelimit.New(cfg.WriteRateLimit),
+		enrichment:  ratelimit.New(cfg.EnrichmentRateLimit),
+		maxBodySize: cfg.MaxBodySize,
+	}
+}
+
+var limits = newRateLimits(&config.Config{})
+
+// rateLimit limits the requests of each client and, for POST, PUT, PATCH and DELETE, the
+// requests of each client to each route of routes, answering 429 with Retry-After once
+// a limit is reached. GraphQL queries are posted but only read, so they are not counted
+// as writes. It also limits request bodies to the configured size; imports upload files
+// and keep their own limit.
+func rateLimit(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r)
+			if !allow(w, r, limits.client, client) {
+				return
+			}
+
+			pattern := routePattern(routes, r)
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+				if !strings.HasSuffix(pattern, "/graphql") && !allow(w, r, limits.write, client+" "+r.Method+" "+pattern) {
+					return
+				}
+			}
+
+			if limits.maxBodySize > 0 && !strings.Contains(pattern, "/import") {
+				if r.ContentLength > limits.maxBodySize {
+					http.Error(w, "Request body is too large, the limit is "+strconv.FormatInt(limits.maxBodySize, 10)+" bytes", http.StatusRequestEntityTooLarge)
+					return
+				}
+				if r.Body != nil {
+					r.Body = http.MaxBytesReader(w, r.Body, limits.maxBodySize)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// enrichmentQuota limits how often each client may create users with data from the
+// external API.
+func enrichmentQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allow(w, r, limits.enrichment, clientKey(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// importEnrichmentQuota is enrichmentQuota for user imports, which are not counted when
+// they skip the lookups or only validate the file. Only the import handler reads those
+// parameters, so no other route may be exempted by them.
+func importEnrichmentQuota(next http.Handler) http.Handler {
+	limited := enrichmentQuota(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		skipEnrichment, _ := strconv.ParseBool(query.Get("skipEnrichment"))
+		dryRun, _ := strconv.ParseBool(query.Get("dryRun"))
+		if skipEnrichment || dryRun {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limited.ServeHTTP(w, r)
+	})
+}
+
+func allow(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, key string) bool {
+	err := limiter.Allow(key)
+	var limitErr *ratelimit.ExceededError
+	if errors.As(err, &limitErr) {
+		logger.Logger.Warn("Rate limit exceeded", zap.String("method", r.Method), zap.String("path", r.URL.Path),
+			zap.Duration("retryAfter", limitErr.RetryAfter))
+		ratelimit.Reject(w, limitErr)
+		return false
+	}
+	return true
+}
+
+// clientKey tells clients apart by IP address. The API does not verify any token, so
+// a header chosen by the client would let it start a fresh bucket with every request.
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// routePattern finds the route a request is for, such as /api/v1/users/{id}, so the
+// requests to one route share a bucket whatever their parameters.
+func routePattern(routes chi.Routes, r *http.Request) string {
+	rctx := chi.NewRouteContext()
+	if !routes.Match(rctx, r.Method, r.URL.Path) {
+		return r.URL.Path
+	}
+	return rctx.RoutePattern()
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"log"
+	"net/http"
+	"os"
+)
+
+// sensitiveQueryParams carry personal data or secrets and are masked in request logs.
+var sensitiveQueryParams = []string{"passportNumber", "address", "token"}
+
+// redactingLogFormatter wraps the chi log formatter so personal data passed in
+// query strings (user filters and updates) and calendar feed tokens never reach
+// the request log.
+type redactingLogFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f redactingLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	query := r.URL.Query()
+	redacted := false
+	for _, param := range sensitiveQueryParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return f.LogFormatter.NewLogEntry(r)
+	}
+
+	logged := *r
+	loggedURL := *r.URL
+	loggedURL.RawQuery = query.Encode()
+	logged.URL = &loggedURL
+	logged.RequestURI = loggedURL.RequestURI()
+	return f.LogFormatter.NewLogEntry(&logged)
+}
+
+func requestLogger() func(http.Handler) http.Handler {
+	return middleware.RequestLogger(redactingLogFormatter{
+		LogFormatter: &middleware.DefaultLogFormatter{Logger: log.New(os.Stdout, "", log.LstdFlags), NoColor: false},
+	})
+}
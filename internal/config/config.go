@@ -1,10 +1,17 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
+	"io/fs"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"time-tracker/internal/logger"
+	"time-tracker/internal/ratelimit"
 )
 
 type Config struct {
@@ -13,21 +20,142 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	EncryptionKeys string
+	BlindIndexKey  string
+
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool
+
+	// IdempotencyWindow is how long the response to a request with an Idempotency-Key is
+	// kept for retries.
+	IdempotencyWindow time.Duration
+
+	// WebhookRetention is how long delivered and failed webhook deliveries are kept in
+	// the outbox after their last attempt.
+	WebhookRetention time.Duration
+
+	// RateLimit limits the requests of each client, told apart by the IP address, over
+	// both REST and gRPC. WriteRateLimit limits its POST, PUT, PATCH and DELETE requests
+	// to each route, and EnrichmentRateLimit its requests that look up users in the
+	// external API. EnrichmentAPIRateLimit caps the lookups themselves, for all clients
+	// together.
+	RateLimit              ratelimit.Rate
+	WriteRateLimit         ratelimit.Rate
+	EnrichmentRateLimit    ratelimit.Rate
+	EnrichmentAPIRateLimit ratelimit.Rate
+
+	// MaxBodySize limits request bodies, except import uploads, in bytes.
+	MaxBodySize int64
+
+	// GRPCAddr is where the gRPC API listens; empty disables it.
+	GRPCAddr string
+
+	// AllowedOrigins are the origins, besides the server's own, whose pages may open the
+	// timer stream over WebSocket, such as https://dashboard.example.com.
+	AllowedOrigins []string
 }
 
+const (
+	defaultIdempotencyWindow = 24 * time.Hour
+	defaultWebhookRetention  = 30 * 24 * time.Hour
+
+	defaultRateLimit              = "600/m"
+	defaultWriteRateLimit         = "60/m"
+	defaultEnrichmentRateLimit    = "20/m"
+	defaultEnrichmentAPIRateLimit = "60/m"
+
+	defaultMaxBodySize = 1 << 20
+
+	defaultGRPCAddr = "localhost:9090"
+)
+
 func LoadConfig() (*Config, error) {
 	logger.Logger.Info("Loading config")
+	// Without a .env file the settings come from the environment alone, as in a container.
 	err := godotenv.Load(".env")
-	if err != nil {
-		return nil, fmt.Errorf("error loading .env file")
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Logger.Info("No .env file, using the environment")
+	} else if err != nil {
+		return nil, fmt.Errorf("error loading .env file: %v", err)
 	}
 
-	logger.Logger.Info("Loaded config")
-	return &Config{
+	cfg := &Config{
 		DBHost:     os.Getenv("DB_HOST"),
 		DBPort:     os.Getenv("DB_PORT"),
 		DBUser:     os.Getenv("DB_USER"),
 		DBPassword: os.Getenv("DB_PASSWORD"),
 		DBName:     os.Getenv("DB_NAME"),
-	}, nil
+
+		EncryptionKeys: os.Getenv("ENCRYPTION_KEYS"),
+		BlindIndexKey:  os.Getenv("BLIND_INDEX_KEY"),
+
+		AutoMigrate: os.Getenv("DB_AUTO_MIGRATE") != "false",
+
+		IdempotencyWindow: defaultIdempotencyWindow,
+		WebhookRetention:  defaultWebhookRetention,
+		MaxBodySize:       defaultMaxBodySize,
+		GRPCAddr:          defaultGRPCAddr,
+	}
+
+	if value := os.Getenv("IDEMPOTENCY_WINDOW"); value != "" {
+		cfg.IdempotencyWindow, err = time.ParseDuration(value)
+		if err != nil || cfg.IdempotencyWindow <= 0 {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_WINDOW %q: must be a positive duration such as 24h", value)
+		}
+	}
+	if value := os.Getenv("WEBHOOK_RETENTION"); value != "" {
+		cfg.WebhookRetention, err = time.ParseDuration(value)
+		if err != nil || cfg.WebhookRetention <= 0 {
+			return nil, fmt.Errorf("invalid WEBHOOK_RETENTION %q: must be a positive duration such as 720h", value)
+		}
+	}
+
+	limits := []struct {
+		name     string
+		fallback string
+		rate     *ratelimit.Rate
+	}{
+		{"RATE_LIMIT", defaultRateLimit, &cfg.RateLimit},
+		{"RATE_LIMIT_WRITE", defaultWriteRateLimit, &cfg.WriteRateLimit},
+		{"ENRICHMENT_RATE_LIMIT", defaultEnrichmentRateLimit, &cfg.EnrichmentRateLimit},
+		{"ENRICHMENT_API_RATE_LIMIT", defaultEnrichmentAPIRateLimit, &cfg.EnrichmentAPIRateLimit},
+	}
+	for _, limit := range limits {
+		value := os.Getenv(limit.name)
+		if value == "" {
+			value = limit.fallback
+		}
+		*limit.rate, err = ratelimit.ParseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", limit.name, err)
+		}
+	}
+
+	if value := os.Getenv("MAX_BODY_SIZE"); value != "" {
+		cfg.MaxBodySize, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || cfg.MaxBodySize <= 0 {
+			return nil, fmt.Errorf("invalid MAX_BODY_SIZE %q: must be a positive number of bytes", value)
+		}
+	}
+
+	// Set but empty, GRPC_ADDR turns the gRPC API off.
+	if value, ok := os.LookupEnv("GRPC_ADDR"); ok {
+		cfg.GRPCAddr = value
+	}
+
+	for _, origin := range strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return nil, fmt.Errorf("invalid ALLOWED_ORIGINS entry %q: must be a scheme and host such as https://example.com", origin)
+		}
+		cfg.AllowedOrigins = append(cfg.AllowedOrigins, u.Scheme+"://"+u.Host)
+	}
+
+	logger.Logger.Info("Loaded config")
+	return cfg, nil
 }
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+func saveAuditEntry(tx *sql.Tx, actor models.Actor, action, targetType string, targetID int, before, after interface{}) error {
+	beforeJSON, err := marshalAuditValue(before)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit before value: %v", err)
+	}
+	afterJSON, err := marshalAuditValue(after)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit after value: %v", err)
+	}
+
+	claimed := sql.NullString{String: actor.Claimed, Valid: actor.Claimed != ""}
+	query := `INSERT INTO audit_log (actor, claimed_actor, action, target_type, target_id, before, after, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(query, actor.Name, claimed, action, targetType, targetID, beforeJSON, afterJSON, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save audit entry: %v", err)
+	}
+	return nil
+}
+
+func marshalAuditValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	// User snapshots keep personal data encrypted, the same way as the users table.
+	if user, ok := value.(models.User); ok {
+		sealed, err := sealUser(user)
+		if err != nil {
+			return nil, err
+		}
+		value = sealed
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// auditColumns are the columns scanAuditEntries reads. The last one tells whether the
+// target is an anonymized user, whose snapshots are redacted on the way out.
+const auditColumns = `id, actor, claimed_actor, action, target_type, target_id, before, after, created_at,
+			  EXISTS (SELECT 1 FROM users WHERE audit_log.target_type = 'user' AND users.id = audit_log.target_id AND users.anonymized_at IS NOT NULL)`
+
+func GetAuditEntries(filter models.AuditFilter, page models.PageRequest) ([]models.AuditEntry, bool, error) {
+	logger.Logger.Info("Getting audit entries")
+	defer logger.Logger.Info("Done getting audit entries")
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	conditions, args := auditConditions(filter)
+
+	key := sortKey{idColumn: "id", desc: true}
+	if page.Cursor != nil {
+		condition, cursorArgs, err := key.keysetCondition(page.Cursor, len(args)+1)
+		if err != nil {
+			return nil, false, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += key.orderAndLimit(page)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries, more := trimPage(entries, page)
+	return entries, more, nil
+}
+
+// GetUserAuditEntries returns the audit trail of a user and of the user's time entries.
+func GetUserAuditEntries(userId int) ([]models.AuditEntry, error) {
+	logger.Logger.Info("Getting user audit entries")
+	defer logger.Logger.Info("Done getting user audit entries")
+
+	query := `SELECT ` + auditColumns + `
+			  FROM audit_log
+			  WHERE (target_type = $1 AND target_id = $3)
+			  OR (target_type = $2 AND target_id IN (SELECT task_id FROM tasks WHERE user_id = $3))
+			  ORDER BY id`
+
+	rows, err := db.Query(query, models.AuditTargetUser, models.AuditTargetTask, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	return scanAuditEntries(rows)
+}
+
+// RecordAuditEntry stores an audit entry for an operation that does not change data,
+// such as reading personal data.
+func RecordAuditEntry(actor models.Actor, action, targetType string, targetID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = saveAuditEntry(tx, actor, action, targetType, targetID, nil, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var claimed sql.NullString
+		var before, after []byte
+		var anonymized bool
+		err := rows.Scan(&entry.ID, &entry.Actor, &claimed, &entry.Action, &entry.TargetType, &entry.TargetID, &before, &after, &entry.CreatedAt, &anonymized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entry.ClaimedActor = claimed.String
+		if entry.TargetType == models.AuditTargetUser {
+			if before, err = openUserSnapshot(before, anonymized); err != nil {
+				return nil, fmt.Errorf("failed to decrypt audit entry %d: %v", entry.ID, err)
+			}
+			if after, err = openUserSnapshot(after, anonymized); err != nil {
+				return nil, fmt.Errorf("failed to decrypt audit entry %d: %v", entry.ID, err)
+			}
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit entries: %v", err)
+	}
+	return entries, nil
+}
+
+func CountAuditEntries(filter models.AuditFilter) (int, error) {
+	logger.Logger.Info("Counting audit entries")
+	defer logger.Logger.Info("Done counting audit entries")
+
+	var count int
+	query := `SELECT COUNT(*) FROM audit_log`
+	conditions, args := auditConditions(filter)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	err := db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit entries: %v", err)
+	}
+	return count, nil
+}
+
+func auditConditions(filter models.AuditFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if filter.Actor != "" {
+		conditions = append(conditions, fmt.Sprintf("actor = $%d", argCount))
+		args = append(args, filter.Actor)
+		argCount++
+	}
+	if filter.ClaimedActor != "" {
+		conditions = append(conditions, fmt.Sprintf("claimed_actor = $%d", argCount))
+		args = append(args, filter.ClaimedActor)
+		argCount++
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, fmt.Sprintf("action = $%d", argCount))
+		args = append(args, filter.Action)
+		argCount++
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, fmt.Sprintf("target_type = $%d", argCount))
+		args = append(args, filter.TargetType)
+		argCount++
+	}
+	if filter.TargetID != 0 {
+		conditions = append(conditions, fmt.Sprintf("target_id = $%d", argCount))
+		args = append(args, filter.TargetID)
+		argCount++
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argCount))
+		args = append(args, filter.From)
+		argCount++
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argCount))
+		args = append(args, filter.To)
+		argCount++
+	}
+
+	return conditions, args
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+var ErrCalendarTokenNotFound = errors.New("calendar token not found")
+
+// CreateCalendarToken issues a new calendar feed token for a user, replacing the
+// previous one. Only a hash of the token is stored, so it is returned exactly once.
+func CreateCalendarToken(actor models.Actor, userId int) (string, error) {
+	logger.Logger.Info("Creating calendar token")
+	defer logger.Logger.Info("Done creating calendar token")
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := getUser(tx, userId); err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO calendar_tokens (user_id, token_hash, created_at)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at`
+	_, err = tx.Exec(query, userId, hashCalendarToken(token), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to save calendar token: %v", err)
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditCalendarTokenCreate, models.AuditTargetUser, userId, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+func RevokeCalendarToken(actor models.Actor, userId int) error {
+	logger.Logger.Info("Revoking calendar token")
+	defer logger.Logger.Info("Done revoking calendar token")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM calendar_tokens WHERE user_id = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke calendar token: %v", err)
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return ErrCalendarTokenNotFound
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditCalendarTokenRevoke, models.AuditTargetUser, userId, nil, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckCalendarToken reports whether token is the current calendar token of the user.
+func CheckCalendarToken(userId int, token string) (bool, error) {
+	var tokenHash string
+	err := db.QueryRow(`SELECT token_hash FROM calendar_tokens WHERE user_id = $1`, userId).Scan(&tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check calendar token: %v", err)
+	}
+	return subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hashCalendarToken(token))) == 1, nil
+}
+
+// GetRunningTasksByPeriod returns the running timers of a user started within the
+// period, using the same bounds as GetTasksByPeriod.
+func GetRunningTasksByPeriod(userId int, startPeriod, endPeriod time.Time) ([]models.Task, error) {
+	logger.Logger.Info("Getting running tasks by period")
+	defer logger.Logger.Info("Done getting running tasks by period")
+	query := `SELECT user_id, task_id, title, description, start_time
+			  FROM tasks
+			  WHERE end_time IS NULL
+			  AND user_id = $3
+			  AND start_time >= $1 AND start_time <= $2`
+
+	rows, err := db.Query(query, startPeriod, endPeriod, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetTasksInRange returns the time entries of a user, including running timers, that
+// overlap the period.
+func GetTasksInRange(userId int, startPeriod, endPeriod time.Time) ([]models.Task, error) {
+	logger.Logger.Info("Getting tasks in range")
+	defer logger.Logger.Info("Done getting tasks in range")
+	query := `SELECT user_id, task_id, title, description, start_time, end_time
+			  FROM tasks
+			  WHERE user_id = $3
+			  AND start_time < $2 AND (end_time IS NULL OR end_time > $1)
+			  ORDER BY start_time`
+
+	rows, err := db.Query(query, startPeriod, endPeriod, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		var endTime sql.NullTime
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &endTime); err != nil {
+			return nil, err
+		}
+		task.EndTime = endTime.Time
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
@@ -7,20 +7,70 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/lib/pq"
+	"sort"
 	"strings"
 	"time"
 	"time-tracker/internal/config"
 	"time-tracker/internal/logger"
 	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
 )
 
 var db *sql.DB
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrTaskNotFound = errors.New("task not found")
+	ErrEmptyUpdate  = errors.New("no fields to update")
+	// ErrVersionMismatch is returned when a conditional change expected a version of the
+	// user or task other than the stored one.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrInvalidCursor is returned for a keyset cursor that does not fit the listing.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrTimerStopped is returned when stopping a timer that is no longer running.
+	ErrTimerStopped = errors.New("timer is already stopped")
+	// ErrPassportExists is returned when a user is saved with the passport number of
+	// another user.
+	ErrPassportExists = errors.New("passport number belongs to another user")
+)
+
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// InitDB connects to the database and, unless DB_AUTO_MIGRATE is false, applies pending
+// migrations.
 func InitDB(config *config.Config) error {
 	logger.Logger.Info("Initializing database connection")
 	defer logger.Logger.Info("Database connection initialized")
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable",
+	if err := Connect(config); err != nil {
+		return err
+	}
+	if !config.AutoMigrate {
+		logger.Logger.Info("Automatic migrations are disabled")
+		return nil
+	}
+
+	m, err := NewMigrate(config)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+	return nil
+}
+
+// Connect opens the database connection without touching the schema.
+func Connect(config *config.Config) error {
+	dsn = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable",
 		config.DBHost, config.DBPort, config.DBUser, config.DBName)
 	var err error
 
@@ -28,20 +78,22 @@ func InitDB(config *config.Config) error {
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
+	return nil
+}
 
+// NewMigrate returns a migrator for the connected database, reading migrations from
+// internal/database/migrations relative to the working directory.
+func NewMigrate(config *config.Config) (*migrate.Migrate, error) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create migrations: %v", err)
+		return nil, fmt.Errorf("failed to create migrations: %v", err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance("file://internal/database/migrations", config.DBName, driver)
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrate: %v", err)
+		return nil, fmt.Errorf("failed to initialize migrate: %v", err)
 	}
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to run migrations: %v", err)
-	}
-	return nil
+	return m, nil
 }
 
 func CheckUserByPassport(passportNumber string) (bool, error) {
@@ -49,74 +101,286 @@ func CheckUserByPassport(passportNumber string) (bool, error) {
 	defer logger.Logger.Info("Checked user by passport number")
 
 	var exist bool
-	query := `SELECT EXISTS (SELECT 1 FROM users WHERE passport_number = $1)`
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE passport_hash = $1)`
 
-	err := db.QueryRow(query, passportNumber).Scan(&exist)
+	err := db.QueryRow(query, pii.BlindIndex(passportNumber)).Scan(&exist)
 	if err != nil {
 		return false, err
 	}
 	return exist, nil
 }
 
-func GetUsers(pageSize, offset int, name, surname, patronymic, passportNumber, address string) ([]models.User, error) {
+func GetUsers(filter models.UserFilter, page models.PageRequest) ([]models.User, bool, error) {
 	logger.Logger.Info("Getting users")
 	defer logger.Logger.Info("Done getting users")
+	if filter.NeedsDecryption() {
+		users, err := GetAllUsers(filter)
+		if err != nil {
+			return nil, false, err
+		}
+		// Cursors end up in URLs and logs, so those of listings sorted by an encrypted
+		// field keep no value: it is looked up again by the ID.
+		if page.Cursor != nil && models.UserDecryptedSortFields[filter.SortBy] {
+			cursor := *page.Cursor
+			cursor.Value, err = decryptedSortValue(users, filter.SortBy, cursor.ID)
+			if err != nil {
+				return nil, false, err
+			}
+			page.Cursor = &cursor
+		}
+		users, more := trimPage(pageDecryptedUsers(users, filter, page), page)
+		return users, more, nil
+	}
+
 	var users []models.User
-	query := `SELECT id, surname, name, patronymic, passport_number, address FROM users`
-	var conditions []string
-	var args []interface{}
-	argCount := 1
+	query := `SELECT id, surname, name, patronymic, passport_number, address, team, version FROM users`
+	conditions, args := userConditions(filter)
 
-	if passportNumber != "" {
-		conditions = append(conditions, fmt.Sprintf("passport_number = $%d", argCount))
-		args = append(args, passportNumber)
-		argCount++
+	key := sortKey{cast: "text", idColumn: "id", desc: filter.SortDesc}
+	if column, ok := models.UserSortFields[filter.SortBy]; ok && column != "id" {
+		key.expr = column
+	}
+	if page.Cursor != nil {
+		condition, cursorArgs, err := key.keysetCondition(page.Cursor, len(args)+1)
+		if err != nil {
+			return nil, false, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
 	}
 
-	if surname != "" {
-		conditions = append(conditions, fmt.Sprintf("surname = $%d", argCount))
-		args = append(args, surname)
-		argCount++
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
+	query += key.orderAndLimit(page)
 
-	if name != "" {
-		conditions = append(conditions, fmt.Sprintf("name = $%d", argCount))
-		args = append(args, name)
-		argCount++
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve users: %v", err)
 	}
+	defer rows.Close()
 
-	if patronymic != "" {
-		conditions = append(conditions, fmt.Sprintf("patronymic = $%d", argCount))
-		args = append(args, patronymic)
-		argCount++
+	for rows.Next() {
+		var user models.User
+		var patronymic, team sql.NullString
+		err = rows.Scan(&user.ID, &user.Surname, &user.Name, &patronymic, &user.PassportNumber, &user.Address, &team, &user.Version)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to scan user: %v", err)
+		}
+		user.Patronymic = patronymic.String
+		user.Team = team.String
+		if err := openUser(&user); err != nil {
+			return nil, false, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve users: %v", err)
 	}
 
-	if address != "" {
-		conditions = append(conditions, fmt.Sprintf("address = $%d", argCount))
-		args = append(args, address)
-		argCount++
+	users, more := trimPage(users, page)
+	return users, more, nil
+}
+
+// pageDecryptedUsers orders decrypted users as the listing is sorted and applies the
+// cursor or offset and the limit of the page, with the lookahead row of orderAndLimit.
+// Values are compared byte by byte rather than by the database collation.
+func pageDecryptedUsers(users []models.User, filter models.UserFilter, page models.PageRequest) []models.User {
+	desc := filter.SortDesc
+	if page.Cursor != nil && page.Cursor.Backward {
+		desc = !desc
+	}
+	// follows reports whether a comes after the position (value, id) in the direction
+	// of travel.
+	follows := func(a models.User, value string, id int) bool {
+		aValue := a.SortValue(filter.SortBy)
+		if desc {
+			return aValue < value || (aValue == value && a.ID < id)
+		}
+		return aValue > value || (aValue == value && a.ID > id)
 	}
+	sort.Slice(users, func(i, j int) bool {
+		return follows(users[j], users[i].SortValue(filter.SortBy), users[i].ID)
+	})
+
+	start := 0
+	switch {
+	case page.Cursor != nil:
+		start = sort.Search(len(users), func(i int) bool {
+			return follows(users[i], page.Cursor.Value, page.Cursor.ID)
+		})
+	case !page.Keyset:
+		start = page.Offset
+		if start > len(users) {
+			start = len(users)
+		}
+	}
+	users = users[start:]
+	if len(users) > page.Limit+1 {
+		users = users[:page.Limit+1]
+	}
+	return users
+}
 
+// decryptedSortValue returns the sort value of the user a cursor points at, which may no
+// longer be among the users that match the filter.
+func decryptedSortValue(users []models.User, sortBy string, userId int) (string, error) {
+	for _, user := range users {
+		if user.ID == userId {
+			return user.SortValue(sortBy), nil
+		}
+	}
+	user, err := getUser(db, userId)
+	if errors.Is(err, ErrUserNotFound) {
+		return "", ErrInvalidCursor
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.SortValue(sortBy), nil
+}
+
+// GetAllUsers returns every user matching the filter, ordered by surname and name.
+func GetAllUsers(filter models.UserFilter) ([]models.User, error) {
+	logger.Logger.Info("Getting all users")
+	defer logger.Logger.Info("Done getting all users")
+
+	query := `SELECT id, surname, name, patronymic, passport_number, address, team, version FROM users`
+	conditions, args := userConditions(filter)
 	if len(conditions) > 0 {
-		query += "WHERE" + strings.Join(conditions, " AND ")
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+	query += " ORDER BY surname, name, id"
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve users1: %v", err)
+		return nil, fmt.Errorf("failed to retrieve users: %v", err)
 	}
+	defer rows.Close()
 
+	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err = rows.Scan(&user.ID, &user.Surname, &user.Name, &user.Patronymic, &user.PassportNumber, &user.Address)
+		var patronymic, team sql.NullString
+		err = rows.Scan(&user.ID, &user.Surname, &user.Name, &patronymic, &user.PassportNumber, &user.Address, &team, &user.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		user.Patronymic = patronymic.String
+		user.Team = team.String
+		if err := openUser(&user); err != nil {
+			return nil, err
+		}
+		if filter.PartialAddress() && !filter.MatchesAddress(user.Address) {
+			continue
+		}
 		users = append(users, user)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to retrieve users2: %v", err)
+		return nil, fmt.Errorf("failed to retrieve users: %v", err)
 	}
 	return users, nil
+}
+
+func CountUsers(filter models.UserFilter) (int, error) {
+	logger.Logger.Info("Counting users")
+	defer logger.Logger.Info("Done counting users")
+
+	if filter.PartialAddress() {
+		users, err := GetAllUsers(filter)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count users: %v", err)
+		}
+		return len(users), nil
+	}
+
+	var count int
+	query := `SELECT COUNT(*) FROM users`
+	conditions, args := userConditions(filter)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
 
+	err := db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %v", err)
+	}
+	return count, nil
+}
+
+func userConditions(filter models.UserFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if len(filter.IDs) > 0 {
+		placeholders := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			placeholders[i] = fmt.Sprintf("$%d", argCount)
+			args = append(args, id)
+			argCount++
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if filter.PassportNumber != "" {
+		conditions = append(conditions, fmt.Sprintf("passport_hash = $%d", argCount))
+		args = append(args, pii.BlindIndex(filter.PassportNumber))
+		argCount++
+	}
+
+	if filter.Team != "" {
+		conditions = append(conditions, fmt.Sprintf("team = $%d", argCount))
+		args = append(args, filter.Team)
+		argCount++
+	}
+
+	// The address is encrypted, so only exact matches go through its blind index; partial
+	// matches are checked after decryption.
+	if filter.Address != "" && !filter.PartialAddress() {
+		conditions = append(conditions, fmt.Sprintf("address_hash = $%d", argCount))
+		args = append(args, pii.BlindIndex(filter.Address))
+		argCount++
+	}
+
+	textFields := []struct {
+		column string
+		value  string
+	}{
+		{"surname", filter.Surname},
+		{"name", filter.Name},
+		{"patronymic", filter.Patronymic},
+	}
+	for _, field := range textFields {
+		if field.value == "" {
+			continue
+		}
+		switch filter.Match {
+		case models.MatchPrefix:
+			conditions = append(conditions, fmt.Sprintf("%s ILIKE $%d", field.column, argCount))
+			args = append(args, escapeLike(field.value)+"%")
+		case models.MatchContains:
+			conditions = append(conditions, fmt.Sprintf("%s ILIKE $%d", field.column, argCount))
+			args = append(args, "%"+escapeLike(field.value)+"%")
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", field.column, argCount))
+			args = append(args, field.value)
+		}
+		argCount++
+	}
+
+	if filter.Query != "" {
+		conditions = append(conditions, fmt.Sprintf("(surname ILIKE $%[1]d OR name ILIKE $%[1]d OR patronymic ILIKE $%[1]d)", argCount))
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		argCount++
+	}
+
+	return conditions, args
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 func CheckTaskExist(taskId int) (bool, error) {
@@ -157,104 +421,327 @@ func CheckUserExist(userID int) (bool, error) {
 	return true, nil
 }
 
-func StartTaskTimer(userId int) error {
+func StartTaskTimer(actor models.Actor, userId int, taskReq models.TaskRequest) (int, error) {
 	logger.Logger.Info("Starting task timer")
 	defer logger.Logger.Info("Done starting task timer")
-	query := `INSERT INTO tasks (user_id, start_time) VALUES ($1, $2)`
 
-	_, err := db.Exec(query, userId, time.Now())
+	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	defer tx.Rollback()
+
+	task := models.Task{UserID: userId, Title: taskReq.Title, Description: taskReq.Description, StartTime: time.Now()}
+	query := `INSERT INTO tasks (user_id, title, description, start_time) VALUES ($1, $2, $3, $4) RETURNING task_id`
+
+	err = tx.QueryRow(query, task.UserID, task.Title, task.Description, task.StartTime).Scan(&task.TaskID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditTimerStart, models.AuditTargetTask, task.TaskID, nil, task)
+	if err != nil {
+		return 0, err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookTimerStarted, task)
+	if err != nil {
+		return 0, err
+	}
+	return task.TaskID, tx.Commit()
 }
 
-func StopTaskTimer(taskID int) error {
+// StopTaskTimer stops a running timer, or returns ErrTimerStopped when it has already
+// been stopped, also by a concurrent request. A non-zero version makes the change
+// conditional: the task must still be at that version, or ErrVersionMismatch is
+// returned.
+func StopTaskTimer(actor models.Actor, taskID, version int) error {
 	logger.Logger.Info("Stopping task timer")
 	defer logger.Logger.Info("Done stopping task timer")
 
-	query := `UPDATE tasks SET end_time = $1 WHERE task_id = $2`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err := db.Exec(query, time.Now(), taskID)
+	before, err := getTask(tx, taskID)
 	if err != nil {
 		return err
 	}
-	return nil
+	if !before.EndTime.IsZero() {
+		return ErrTimerStopped
+	}
+
+	after := before
+	after.EndTime = time.Now()
+	after.Version++
+	query := `UPDATE tasks SET end_time = $1, version = version + 1
+			  WHERE task_id = $2 AND end_time IS NULL AND ($3 = 0 OR version = $3)`
+
+	result, err := tx.Exec(query, after.EndTime, taskID, version)
+	if err != nil {
+		return err
+	}
+	if err := expectChanged(result); err != nil {
+		// The update waited for a concurrent stop to commit and no longer matched.
+		current, getErr := getTask(tx, taskID)
+		if getErr == nil && !current.EndTime.IsZero() {
+			return ErrTimerStopped
+		}
+		return err
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditTimerStop, models.AuditTargetTask, taskID, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookTimerStopped, after)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func DeleteUser(userId int) error {
+// DeleteUser deletes a user with all tasks. A non-zero version makes the deletion
+// conditional, as in StopTaskTimer. Running timers of the user are reported stopped
+// to live update streams.
+func DeleteUser(actor models.Actor, userId, version int) error {
 	logger.Logger.Info("Deleting user")
 	defer logger.Logger.Info("Done deleting user")
 
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(tx, userId)
+	if err != nil {
+		return err
+	}
+
+	timers, err := lockRunningTimers(tx, userId)
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM tasks WHERE user_id = $1`
-	_, err := db.Exec(query, userId)
+	_, err = tx.Exec(query, userId)
 	if err != nil {
 		return err
 	}
 
-	query = `DELETE FROM users WHERE id = $1`
-	_, err = db.Exec(query, userId)
+	query = `DELETE FROM users WHERE id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := tx.Exec(query, userId, version)
 	if err != nil {
 		return err
 	}
+	if err := expectChanged(result); err != nil {
+		return err
+	}
 
-	return nil
+	err = saveAuditEntry(tx, actor, models.AuditUserDelete, models.AuditTargetUser, userId, before, nil)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookUserDeleted, models.NewWebhookUser(before))
+	if err != nil {
+		return err
+	}
+
+	err = notifyTimersStopped(tx, timers, time.Now())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func UpdateUser(userId int, surname, name, patronymic, passportNumber, address string) error {
+// UpdateUser applies a partial update and returns the updated user. Cleared fields
+// are stored as NULL. A non-zero version makes the update conditional, as in
+// StopTaskTimer. An anonymized user cannot be updated: ErrUserAnonymized is returned.
+func UpdateUser(actor models.Actor, userId, version int, update models.UserUpdate) (models.User, error) {
 	logger.Logger.Info("Updating user")
 	defer logger.Logger.Info("Done updating user")
+	if update.Empty() {
+		return models.User{}, ErrEmptyUpdate
+	}
 	query := `UPDATE users SET `
 
 	var conditions []string
 	var args []interface{}
 	var argCount = 1
 
-	if name != "" {
+	if update.Name.Set {
 		conditions = append(conditions, fmt.Sprintf("name = $%d", argCount))
-		args = append(args, name)
+		args = append(args, update.Name.Value)
 		argCount++
 	}
-	if surname != "" {
+	if update.Surname.Set {
 		conditions = append(conditions, fmt.Sprintf("surname = $%d", argCount))
-		args = append(args, surname)
+		args = append(args, update.Surname.Value)
 		argCount++
 	}
 
-	if patronymic != "" {
+	if update.Patronymic.Set {
 		conditions = append(conditions, fmt.Sprintf("patronymic = $%d", argCount))
-		args = append(args, patronymic)
+		args = append(args, nullString(update.Patronymic))
 		argCount++
 	}
-	if passportNumber != "" {
-		conditions = append(conditions, fmt.Sprintf("passport_number = $%d", argCount))
-		args = append(args, passportNumber)
-		argCount++
+	if update.PassportNumber.Set {
+		encrypted, err := pii.Encrypt(update.PassportNumber.Value)
+		if err != nil {
+			return models.User{}, fmt.Errorf("failed to encrypt passport number: %v", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("passport_number = $%d, passport_hash = $%d", argCount, argCount+1))
+		args = append(args, encrypted, pii.BlindIndex(update.PassportNumber.Value))
+		argCount += 2
 	}
-	if address != "" {
-		conditions = append(conditions, fmt.Sprintf("address = $%d", argCount))
-		args = append(args, address)
+	if update.Address.Set {
+		encrypted, err := pii.Encrypt(update.Address.Value)
+		if err != nil {
+			return models.User{}, fmt.Errorf("failed to encrypt address: %v", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("address = $%d, address_hash = $%d", argCount, argCount+1))
+		args = append(args, encrypted, pii.BlindIndex(update.Address.Value))
+		argCount += 2
+	}
+
+	if update.Team.Set {
+		conditions = append(conditions, fmt.Sprintf("team = $%d", argCount))
+		args = append(args, nullString(update.Team))
 		argCount++
 	}
 
+	conditions = append(conditions, "version = version + 1")
 	query += strings.Join(conditions, ", ")
-	query += fmt.Sprintf(" WHERE id = %d", userId)
+	query += fmt.Sprintf(" WHERE id = $%d AND ($%d = 0 OR version = $%d)", argCount, argCount+1, argCount+1)
+	args = append(args, userId, version)
 
-	fmt.Println(query, args)
+	tx, err := db.Begin()
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
 
-	_, err := db.Exec(query, args...)
+	if err := lockActiveUser(tx, userId); err != nil {
+		return models.User{}, err
+	}
+
+	before, err := getUser(tx, userId)
 	if err != nil {
-		return err
+		return models.User{}, err
 	}
 
+	result, err := tx.Exec(query, args...)
+	if isPassportTaken(err) {
+		return models.User{}, ErrPassportExists
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := expectChanged(result); err != nil {
+		return models.User{}, err
+	}
+
+	after, err := getUser(tx, userId)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditUserUpdate, models.AuditTargetUser, userId, before, after)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookUserUpdated, models.NewWebhookUser(after))
+	if err != nil {
+		return models.User{}, err
+	}
+	return after, tx.Commit()
+}
+
+// isPassportTaken reports whether err violates the unique index on passport numbers.
+func isPassportTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_passport_hash_idx"
+}
+
+// expectChanged returns ErrVersionMismatch when a conditional statement matched no row.
+// The row itself is known to exist, as it has been read in the same transaction.
+func expectChanged(result sql.Result) error {
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return ErrVersionMismatch
+	}
 	return nil
 }
 
+// nullString turns a cleared field into NULL.
+func nullString(s models.OptionalString) sql.NullString {
+	return sql.NullString{String: s.Value, Valid: !s.Clear()}
+}
+
+func GetUser(userId int) (models.User, error) {
+	logger.Logger.Info("Getting user")
+	defer logger.Logger.Info("Done getting user")
+
+	return getUser(db, userId)
+}
+
+func getUser(q querier, userId int) (models.User, error) {
+	var user models.User
+	var patronymic, team sql.NullString
+	query := `SELECT id, surname, name, patronymic, passport_number, address, team, version FROM users WHERE id = $1`
+
+	err := q.QueryRow(query, userId).Scan(&user.ID, &user.Surname, &user.Name, &patronymic, &user.PassportNumber, &user.Address, &team, &user.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	user.Patronymic = patronymic.String
+	user.Team = team.String
+	return user, openUser(&user)
+}
+
+// GetTask returns a time entry with its times in the server's time zone.
+func GetTask(taskID int) (models.Task, error) {
+	task, err := getTask(db, taskID)
+	if err != nil {
+		return task, err
+	}
+	task.StartTime, task.EndTime = models.WallClock(task.StartTime), models.WallClock(task.EndTime)
+	return task, nil
+}
+
+func getTask(q querier, taskID int) (models.Task, error) {
+	var task models.Task
+	var endTime sql.NullTime
+	query := `SELECT user_id, task_id, title, description, start_time, end_time, version FROM tasks WHERE task_id = $1`
+
+	err := q.QueryRow(query, taskID).Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &endTime, &task.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return task, ErrTaskNotFound
+	}
+	if err != nil {
+		return task, err
+	}
+	task.EndTime = endTime.Time
+	return task, nil
+}
+
 func GetTasks(userId int) ([]models.Task, error) {
 	logger.Logger.Info("Getting tasks")
 	defer logger.Logger.Info("Done getting tasks")
 
-	query := `SELECT user_id, task_id, start_time, end_time
+	query := `SELECT user_id, task_id, title, description, start_time, end_time
  			  FROM tasks
  			  WHERE end_time IS NOT NULL
  			  AND user_id = $1`
@@ -268,7 +755,7 @@ func GetTasks(userId int) ([]models.Task, error) {
 	var tasks []models.Task
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.UserID, &task.TaskID, &task.StartTime, &task.EndTime); err != nil {
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &task.EndTime); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -283,7 +770,7 @@ func GetTasks(userId int) ([]models.Task, error) {
 func GetTasksByPeriod(userId int, startPeriod, endPeriod time.Time) ([]models.Task, error) {
 	logger.Logger.Info("Getting tasks by period")
 	defer logger.Logger.Info("Done getting tasks by period")
-	query := `SELECT user_id, task_id, start_time, end_time
+	query := `SELECT user_id, task_id, title, description, start_time, end_time
  			  FROM tasks
  			  WHERE end_time IS NOT NULL
  			  AND user_id = $3
@@ -298,7 +785,7 @@ func GetTasksByPeriod(userId int, startPeriod, endPeriod time.Time) ([]models.Ta
 	var tasks []models.Task
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.UserID, &task.TaskID, &task.StartTime, &task.EndTime); err != nil {
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &task.EndTime); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -309,15 +796,237 @@ func GetTasksByPeriod(userId int, startPeriod, endPeriod time.Time) ([]models.Ta
 	return tasks, nil
 }
 
-func SaveUser(user models.User) error {
+func GetTasksPage(filter models.TaskFilter, page models.PageRequest) ([]models.Task, bool, error) {
+	logger.Logger.Info("Getting tasks page")
+	defer logger.Logger.Info("Done getting tasks page")
+
+	query := `SELECT user_id, task_id, title, description, start_time, end_time, version FROM tasks`
+	conditions, args := taskConditions(filter)
+
+	key := sortKey{expr: "end_time - start_time", cast: "interval", idColumn: "task_id", desc: true}
+	if page.Cursor != nil {
+		condition, cursorArgs, err := key.keysetCondition(page.Cursor, len(args)+1)
+		if err != nil {
+			return nil, false, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += key.orderAndLimit(page)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &task.EndTime, &task.Version); err != nil {
+			return nil, false, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	tasks, more := trimPage(tasks, page)
+	return tasks, more, nil
+}
+
+// GetTasksByUsers returns the time entries of several users, running ones included,
+// in the order they were started and with their times in the server's time zone. A
+// period limits them to the entries started within it, as in TaskFilter.
+func GetTasksByUsers(userIDs []int, start, end time.Time) ([]models.Task, error) {
+	logger.Logger.Info("Getting tasks of users")
+	defer logger.Logger.Info("Done getting tasks of users")
+
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	query := `SELECT user_id, task_id, title, description, start_time, end_time, version
+			  FROM tasks
+			  WHERE user_id IN (` + strings.Join(placeholders, ", ") + `)`
+	if !start.IsZero() && !end.IsZero() {
+		query += fmt.Sprintf(" AND start_time >= $%d AND start_time <= $%d", len(args)+1, len(args)+2)
+		args = append(args, start, end)
+	}
+	query += " ORDER BY start_time, task_id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tasks: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		var endTime sql.NullTime
+		err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &endTime, &task.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %v", err)
+		}
+		task.StartTime, task.EndTime = models.WallClock(task.StartTime), models.WallClock(endTime.Time)
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve tasks: %v", err)
+	}
+	return tasks, nil
+}
+
+func CountTasks(filter models.TaskFilter) (int, error) {
+	logger.Logger.Info("Counting tasks")
+	defer logger.Logger.Info("Done counting tasks")
+
+	var count int
+	conditions, args := taskConditions(filter)
+	query := `SELECT COUNT(*) FROM tasks WHERE ` + strings.Join(conditions, " AND ")
+
+	err := db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func taskConditions(filter models.TaskFilter) ([]string, []interface{}) {
+	conditions := []string{"end_time IS NOT NULL", "user_id = $1"}
+	args := []interface{}{filter.UserID}
+
+	if !filter.Start.IsZero() && !filter.End.IsZero() {
+		conditions = append(conditions, "start_time >= $2 AND start_time <= $3")
+		args = append(args, filter.Start, filter.End)
+	}
+	return conditions, args
+}
+
+// SaveUser stores a new user and returns its ID.
+func SaveUser(actor models.Actor, user models.User) (int, error) {
 	logger.Logger.Info("Saving user")
 	defer logger.Logger.Info("Done saving user")
 
-	query := `INSERT INTO users (surname, name, patronymic, address, passport_number)
- 			  VALUES ($1, $2, $3, $4, $5)`
-	_, err := db.Exec(query, user.Surname, user.Name, user.Patronymic, user.Address, user.PassportNumber)
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to save user: %v", err)
+		return 0, fmt.Errorf("failed to save user: %v", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	userId, err := saveUser(tx, actor, user)
+	if err != nil {
+		return 0, err
+	}
+	return userId, tx.Commit()
+}
+
+func saveUser(tx *sql.Tx, actor models.Actor, user models.User) (int, error) {
+	sealed, err := sealUser(user)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO users (surname, name, patronymic, address, passport_number, address_hash, passport_hash, team)
+ 			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+ 			  RETURNING id`
+	err = tx.QueryRow(query, user.Surname, user.Name, user.Patronymic, sealed.Address, sealed.PassportNumber,
+		pii.BlindIndex(user.Address), pii.BlindIndex(user.PassportNumber), user.Team).Scan(&user.ID)
+	if isPassportTaken(err) {
+		return 0, ErrPassportExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to save user: %v", err)
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditUserCreate, models.AuditTargetUser, user.ID, nil, user)
+	if err != nil {
+		return 0, err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookUserCreated, models.NewWebhookUser(user))
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// GetTimer returns a time entry with the team of its user.
+func GetTimer(taskID int) (models.Timer, error) {
+	query := `SELECT t.task_id, t.user_id, u.team, t.title, t.start_time, t.end_time
+			  FROM tasks t JOIN users u ON u.id = t.user_id
+			  WHERE t.task_id = $1`
+	timer, err := scanTimer(db.QueryRow(query, taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return timer, ErrTaskNotFound
+	}
+	return timer, err
+}
+
+// GetRunningTimers returns the running timers matching the filter, oldest first.
+func GetRunningTimers(filter models.TimerFilter) ([]models.Timer, error) {
+	logger.Logger.Info("Getting running timers")
+	defer logger.Logger.Info("Done getting running timers")
+
+	query := `SELECT t.task_id, t.user_id, u.team, t.title, t.start_time, t.end_time
+			  FROM tasks t JOIN users u ON u.id = t.user_id
+			  WHERE t.end_time IS NULL`
+	var args []interface{}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND t.user_id = $%d", len(args))
+	}
+	if filter.Team != "" {
+		args = append(args, filter.Team)
+		query += fmt.Sprintf(" AND u.team = $%d", len(args))
+	}
+	query += " ORDER BY t.start_time, t.task_id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve running timers: %v", err)
+	}
+	defer rows.Close()
+
+	var timers []models.Timer
+	for rows.Next() {
+		timer, err := scanTimer(rows)
+		if err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve running timers: %v", err)
+	}
+	return timers, nil
+}
+
+func scanTimer(row scanner) (models.Timer, error) {
+	var timer models.Timer
+	var team sql.NullString
+	var endTime sql.NullTime
+	err := row.Scan(&timer.TaskID, &timer.UserID, &team, &timer.Title, &timer.StartTime, &endTime)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return timer, err
+		}
+		return timer, fmt.Errorf("failed to scan timer: %v", err)
+	}
+	timer.Team = team.String
+	timer.StartTime = models.WallClock(timer.StartTime)
+	if endTime.Valid {
+		end := models.WallClock(endTime.Time)
+		timer.EndTime = &end
+	}
+	return timer, nil
 }
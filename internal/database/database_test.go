@@ -0,0 +1,29 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"testing"
+)
+
+func TestIsPassportTaken(t *testing.T) {
+	passportViolation := &pq.Error{Code: "23505", Constraint: "users_passport_hash_idx"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"passport index", passportViolation, true},
+		{"wrapped", fmt.Errorf("failed to save user: %w", passportViolation), true},
+		{"other unique index", &pq.Error{Code: "23505", Constraint: "users_pkey"}, false},
+		{"other error on the index", &pq.Error{Code: "23502", Constraint: "users_passport_hash_idx"}, false},
+		{"not a database error", errors.New("users_passport_hash_idx"), false},
+		{"no error", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isPassportTaken(tt.err); got != tt.want {
+			t.Errorf("%s: isPassportTaken = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"time-tracker/internal/pii"
+)
+
+// IdempotentRequest is what is stored for an Idempotency-Key: the fingerprint of the
+// request that first used it and, once that request has finished, its response.
+type IdempotentRequest struct {
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// ReserveIdempotencyKey claims the client's key for a request with the given
+// fingerprint. Keys are scoped by client, so clients that happen to pick the same key
+// neither block nor see each other's responses. It returns true when the request is to
+// be processed: the key is new, or it was last used before expiredBefore. Otherwise it
+// returns what is stored for the key.
+func ReserveIdempotencyKey(client, key, fingerprint string, expiredBefore time.Time) (IdempotentRequest, bool, error) {
+	query := `INSERT INTO idempotency_keys (client, key, fingerprint, created_at) VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (client, key) DO UPDATE
+			  SET fingerprint = EXCLUDED.fingerprint, status = NULL, header = NULL, body = NULL,
+			      created_at = EXCLUDED.created_at
+			  WHERE idempotency_keys.created_at < $5
+			  RETURNING key`
+	var reserved string
+	err := db.QueryRow(query, client, key, fingerprint, time.Now(), expiredBefore).Scan(&reserved)
+	if err == nil {
+		return IdempotentRequest{}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return IdempotentRequest{}, false, fmt.Errorf("failed to reserve idempotency key: %v", err)
+	}
+
+	var stored IdempotentRequest
+	var status sql.NullInt64
+	var header, body sql.NullString
+	query = `SELECT fingerprint, status, header, body FROM idempotency_keys WHERE client = $1 AND key = $2`
+	err = db.QueryRow(query, client, key).Scan(&stored.Fingerprint, &status, &header, &body)
+	if err != nil {
+		return stored, false, fmt.Errorf("failed to get idempotency key: %v", err)
+	}
+	if !status.Valid {
+		return stored, false, nil
+	}
+
+	stored.Completed = true
+	stored.Status = int(status.Int64)
+	if err := json.Unmarshal([]byte(header.String), &stored.Header); err != nil {
+		return stored, false, fmt.Errorf("failed to parse stored response header: %v", err)
+	}
+	// Responses may carry personal data, so they are stored encrypted like the users.
+	plaintext, err := pii.Decrypt(body.String)
+	if err != nil {
+		return stored, false, fmt.Errorf("failed to decrypt stored response: %v", err)
+	}
+	stored.Body = []byte(plaintext)
+	return stored, false, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request that reserved the client's
+// key.
+func CompleteIdempotencyKey(client, key string, status int, header http.Header, body []byte) error {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response header: %v", err)
+	}
+	sealed, err := pii.Encrypt(string(body))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt response: %v", err)
+	}
+
+	query := `UPDATE idempotency_keys SET status = $1, header = $2, body = $3 WHERE client = $4 AND key = $5`
+	_, err = db.Exec(query, status, string(encodedHeader), sealed, client, key)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %v", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a key whose request failed without a response worth
+// replaying, so a retry with the key is processed again.
+func ReleaseIdempotencyKey(client, key string) error {
+	_, err := db.Exec(`DELETE FROM idempotency_keys WHERE client = $1 AND key = $2 AND status IS NULL`, client, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes the keys last used before expiredBefore and returns how
+// many there were.
+func PurgeIdempotencyKeys(expiredBefore time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM idempotency_keys WHERE created_at < $1`, expiredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %v", err)
+	}
+	return result.RowsAffected()
+}
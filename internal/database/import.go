@@ -0,0 +1,200 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
+)
+
+// importBatchSize bounds the number of placeholders in a single lookup query.
+const importBatchSize = 1000
+
+// queryInBatches runs query, whose "%s" stands for a list of placeholders, for each batch
+// of values and passes every returned row to scan.
+func queryInBatches(query string, values []interface{}, scan func(rows *sql.Rows) error) error {
+	for start := 0; start < len(values); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		batch := values[start:end]
+		placeholders := make([]string, len(batch))
+		for i := range batch {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+
+		rows, err := db.Query(fmt.Sprintf(query, strings.Join(placeholders, ", ")), batch...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserIDsByPassports maps the given normalized passport numbers to the IDs of the
+// users that hold them. Unknown passports are absent from the map.
+func GetUserIDsByPassports(passports []string) (map[string]int, error) {
+	logger.Logger.Info("Getting user IDs by passports")
+	defer logger.Logger.Info("Done getting user IDs by passports")
+
+	byHash := make(map[string]string, len(passports))
+	hashes := make([]interface{}, 0, len(passports))
+	for _, passport := range passports {
+		hash := pii.BlindIndex(passport)
+		if _, ok := byHash[hash]; !ok {
+			byHash[hash] = passport
+			hashes = append(hashes, hash)
+		}
+	}
+
+	ids := make(map[string]int, len(passports))
+	err := queryInBatches(`SELECT id, passport_hash FROM users WHERE passport_hash IN (%s)`, hashes, func(rows *sql.Rows) error {
+		var id int
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return err
+		}
+		ids[byHash[hash]] = id
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up passports: %v", err)
+	}
+	return ids, nil
+}
+
+// GetExistingUserIDs reports which of the given user IDs exist.
+func GetExistingUserIDs(userIDs []int) (map[int]bool, error) {
+	logger.Logger.Info("Checking user IDs")
+	defer logger.Logger.Info("Done checking user IDs")
+
+	existing := make(map[int]bool, len(userIDs))
+	err := queryInBatches(`SELECT id FROM users WHERE id IN (%s)`, intValues(userIDs), func(rows *sql.Rows) error {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		existing[id] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user IDs: %v", err)
+	}
+	return existing, nil
+}
+
+// GetTaskStarts maps the start times (Unix seconds of the wall clock in the server's
+// zone) of the time entries of the given users to the task IDs, so an import can
+// recognize entries it has already stored.
+func GetTaskStarts(userIDs []int) (map[int]map[int64]int, error) {
+	logger.Logger.Info("Getting task start times")
+	defer logger.Logger.Info("Done getting task start times")
+
+	starts := make(map[int]map[int64]int, len(userIDs))
+	err := queryInBatches(`SELECT user_id, task_id, start_time FROM tasks WHERE user_id IN (%s)`, intValues(userIDs), func(rows *sql.Rows) error {
+		var userID, taskID int
+		var startTime time.Time
+		if err := rows.Scan(&userID, &taskID, &startTime); err != nil {
+			return err
+		}
+		if starts[userID] == nil {
+			starts[userID] = map[int64]int{}
+		}
+		starts[userID][models.WallClock(startTime).Unix()] = taskID
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve task start times: %v", err)
+	}
+	return starts, nil
+}
+
+func intValues(ints []int) []interface{} {
+	values := make([]interface{}, len(ints))
+	for i, v := range ints {
+		values[i] = v
+	}
+	return values
+}
+
+// ImportUsers stores users in a single transaction: either all of them are created or
+// none. Each creation is audited like a regular one.
+func ImportUsers(actor models.Actor, users []models.User) ([]int, error) {
+	logger.Logger.Info("Importing users")
+	defer logger.Logger.Info("Done importing users")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(users))
+	for _, user := range users {
+		id, err := saveUser(tx, actor, user)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+// ImportTasks stores completed time entries in a single transaction: either all of
+// them are imported or none.
+func ImportTasks(actor models.Actor, tasks []models.Task) ([]int, error) {
+	logger.Logger.Info("Importing tasks")
+	defer logger.Logger.Info("Done importing tasks")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	checked := map[int]bool{}
+	query := `INSERT INTO tasks (user_id, title, description, start_time, end_time) VALUES ($1, $2, $3, $4, $5) RETURNING task_id`
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		if !checked[task.UserID] {
+			// The lock keeps the user from being deleted until the entries are stored.
+			var id int
+			err = tx.QueryRow(`SELECT id FROM users WHERE id = $1 FOR SHARE`, task.UserID).Scan(&id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: %d", ErrUserNotFound, task.UserID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			checked[task.UserID] = true
+		}
+
+		err = tx.QueryRow(query, task.UserID, task.Title, task.Description, task.StartTime, task.EndTime).Scan(&task.TaskID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import task: %v", err)
+		}
+
+		err = saveAuditEntry(tx, actor, models.AuditTaskImport, models.AuditTargetTask, task.TaskID, nil, task)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, task.TaskID)
+	}
+	return ids, tx.Commit()
+}
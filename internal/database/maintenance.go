@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// CloseStaleTimers stops the timers that have been running for longer than maxDuration,
+// usually because someone forgot to stop them. Each one is closed at start + maxDuration
+// rather than now, so a forgotten timer does not inflate reports. With dryRun the
+// timers are only returned.
+func CloseStaleTimers(actor models.Actor, maxDuration time.Duration, dryRun bool) ([]models.Task, error) {
+	logger.Logger.Info("Closing stale timers")
+	defer logger.Logger.Info("Done closing stale timers")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT t.user_id, t.task_id, t.title, t.description, t.start_time, t.version, u.team
+			  FROM tasks t JOIN users u ON u.id = t.user_id
+			  WHERE t.end_time IS NULL AND t.start_time < $1
+			  ORDER BY t.start_time, t.task_id
+			  FOR UPDATE OF t`
+	rows, err := tx.Query(query, time.Now().Add(-maxDuration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stale timers: %v", err)
+	}
+
+	var stale []models.Task
+	teams := make(map[int]string)
+	for rows.Next() {
+		var task models.Task
+		var team sql.NullString
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &task.Version, &team); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan timer: %v", err)
+		}
+		task.StartTime = models.WallClock(task.StartTime)
+		stale = append(stale, task)
+		teams[task.UserID] = team.String
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find stale timers: %v", err)
+	}
+
+	closed := make([]models.Task, 0, len(stale))
+	timers := make([]models.Timer, 0, len(stale))
+	for _, before := range stale {
+		if dryRun {
+			after := before
+			after.EndTime = before.StartTime.Add(maxDuration)
+			after.Version++
+			closed = append(closed, after)
+			continue
+		}
+
+		after, err := closeTimer(tx, actor, before, before.StartTime.Add(maxDuration))
+		if err != nil {
+			return nil, err
+		}
+		closed = append(closed, after)
+		timers = append(timers, taskTimer(after, teams[after.UserID]))
+	}
+
+	if dryRun {
+		return closed, nil
+	}
+	// The server relays the notifications to its live update streams, which would
+	// otherwise keep ticking for timers closed by this separate process.
+	err = notifyTimersStopped(tx, timers, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return closed, tx.Commit()
+}
+
+// closeTimer ends a running time entry at the given time, with the audit entry and the
+// webhook event of a stop, and returns the closed entry.
+func closeTimer(tx *sql.Tx, actor models.Actor, before models.Task, end time.Time) (models.Task, error) {
+	after := before
+	after.EndTime = end
+	after.Version++
+
+	_, err := tx.Exec(`UPDATE tasks SET end_time = $1, version = version + 1 WHERE task_id = $2`, after.EndTime, after.TaskID)
+	if err != nil {
+		return after, fmt.Errorf("failed to close timer %d: %v", after.TaskID, err)
+	}
+	err = saveAuditEntry(tx, actor, models.AuditTimerStop, models.AuditTargetTask, after.TaskID, before, after)
+	if err != nil {
+		return after, err
+	}
+	err = enqueueWebhookEvent(tx, models.WebhookTimerStopped, after)
+	if err != nil {
+		return after, err
+	}
+	return after, nil
+}
+
+// taskTimer is the live update view of a time entry.
+func taskTimer(task models.Task, team string) models.Timer {
+	timer := models.Timer{TaskID: task.TaskID, UserID: task.UserID, Team: team, Title: task.Title, StartTime: task.StartTime}
+	if !task.EndTime.IsZero() {
+		end := task.EndTime
+		timer.EndTime = &end
+	}
+	return timer
+}
+
+// Analyze refreshes the planner statistics of all tables, which keeps the search and
+// report queries on their indexes after large imports or deletions.
+func Analyze() error {
+	logger.Logger.Info("Analyzing tables")
+	defer logger.Logger.Info("Done analyzing tables")
+
+	if _, err := db.Exec(`ANALYZE`); err != nil {
+		return fmt.Errorf("failed to analyze tables: %v", err)
+	}
+	return nil
+}
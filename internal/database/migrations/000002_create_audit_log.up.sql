@@ -0,0 +1,27 @@
+CREATE TABLE audit_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    actor       VARCHAR(100) NOT NULL,
+    action      VARCHAR(50)  NOT NULL,
+    target_type VARCHAR(50)  NOT NULL,
+    target_id   INT          NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    created_at  TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX audit_log_target_idx ON audit_log (target_type, target_id);
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+CREATE FUNCTION audit_log_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
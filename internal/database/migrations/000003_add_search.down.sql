@@ -0,0 +1,16 @@
+-- pg_trgm is left installed: it may have existed before this migration.
+DROP INDEX tasks_search_vector_idx;
+DROP INDEX tasks_search_text_trgm_idx;
+DROP INDEX IF EXISTS users_address_trgm_idx;
+DROP INDEX users_search_vector_idx;
+DROP INDEX users_search_text_trgm_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN search_vector,
+    DROP COLUMN search_text,
+    DROP COLUMN description,
+    DROP COLUMN title;
+
+ALTER TABLE users
+    DROP COLUMN search_vector,
+    DROP COLUMN search_text;
@@ -0,0 +1,25 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE tasks
+    ADD COLUMN title       VARCHAR(200) NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT         NOT NULL DEFAULT '';
+
+ALTER TABLE users
+    ADD COLUMN search_text   TEXT GENERATED ALWAYS AS (
+        surname || ' ' || name || ' ' || COALESCE(patronymic, '')
+        ) STORED,
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('simple', surname || ' ' || name || ' ' || COALESCE(patronymic, '') || ' ' || address)
+        ) STORED;
+
+ALTER TABLE tasks
+    ADD COLUMN search_text   TEXT GENERATED ALWAYS AS (title || ' ' || description) STORED,
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('simple', title || ' ' || description)
+        ) STORED;
+
+CREATE INDEX users_search_text_trgm_idx ON users USING GIN (search_text gin_trgm_ops);
+CREATE INDEX users_search_vector_idx ON users USING GIN (search_vector);
+CREATE INDEX users_address_trgm_idx ON users USING GIN (address gin_trgm_ops);
+CREATE INDEX tasks_search_text_trgm_idx ON tasks USING GIN (search_text gin_trgm_ops);
+CREATE INDEX tasks_search_vector_idx ON tasks USING GIN (search_vector);
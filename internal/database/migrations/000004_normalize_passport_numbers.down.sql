@@ -0,0 +1,2 @@
+-- The original formatting of passport numbers is not kept, and the normalized form is
+-- accepted by every version of the API, so there is nothing to undo.
@@ -0,0 +1,11 @@
+-- Bring existing passport numbers to the "1234 567890" form used by the API.
+-- Rows whose normalized value is already taken are left for manual review.
+UPDATE users u
+SET passport_number = substr(d.digits, 1, 4) || ' ' || substr(d.digits, 5)
+FROM (SELECT id, regexp_replace(passport_number, '\s', '', 'g') AS digits FROM users) d
+WHERE u.id = d.id
+  AND d.digits ~ '^\d{10}$'
+  AND u.passport_number <> substr(d.digits, 1, 4) || ' ' || substr(d.digits, 5)
+  AND NOT EXISTS (SELECT 1
+                  FROM users o
+                  WHERE o.passport_number = substr(d.digits, 1, 4) || ' ' || substr(d.digits, 5));
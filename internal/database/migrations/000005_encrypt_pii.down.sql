@@ -0,0 +1,9 @@
+-- Passport numbers and addresses are encrypted by the application and cannot be
+-- decrypted in SQL. Rolling back the schema would leave ciphertext where older versions
+-- expect plaintext, so this migration is irreversible.
+DO
+$$
+    BEGIN
+        RAISE EXCEPTION 'migration 5 (encrypt_pii) cannot be rolled back: personal data is encrypted';
+    END
+$$;
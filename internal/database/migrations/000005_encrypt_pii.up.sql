@@ -0,0 +1,20 @@
+-- passport_number and address hold ciphertext written by the application.
+-- Equality lookups and uniqueness go through keyed hashes (blind indexes).
+ALTER TABLE users
+    DROP CONSTRAINT users_passport_number_key,
+    ALTER COLUMN passport_number TYPE TEXT,
+    ADD COLUMN passport_hash CHAR(64),
+    ADD COLUMN address_hash  CHAR(64);
+
+CREATE UNIQUE INDEX users_passport_hash_idx ON users (passport_hash);
+CREATE INDEX users_address_hash_idx ON users (address_hash);
+
+-- Encrypted addresses can no longer be searched inside the database.
+DROP INDEX users_address_trgm_idx;
+ALTER TABLE users
+    DROP COLUMN search_vector;
+ALTER TABLE users
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('simple', surname || ' ' || name || ' ' || COALESCE(patronymic, ''))
+        ) STORED;
+CREATE INDEX users_search_vector_idx ON users USING GIN (search_vector);
@@ -0,0 +1,4 @@
+DROP INDEX users_team_idx;
+
+ALTER TABLE users
+    DROP COLUMN team;
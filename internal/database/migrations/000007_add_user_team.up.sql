@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN team VARCHAR(100);
+
+CREATE INDEX users_team_idx ON users (team);
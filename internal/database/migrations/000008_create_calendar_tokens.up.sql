@@ -0,0 +1,7 @@
+CREATE TABLE calendar_tokens
+(
+    user_id    INT       PRIMARY KEY,
+    token_hash CHAR(64)  NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
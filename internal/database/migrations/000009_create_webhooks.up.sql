@@ -0,0 +1,30 @@
+CREATE TABLE webhooks
+(
+    id         SERIAL PRIMARY KEY,
+    url        TEXT      NOT NULL,
+    secret     TEXT      NOT NULL,
+    events     JSONB     NOT NULL DEFAULT '[]',
+    active     BOOLEAN   NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE webhook_outbox
+(
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       INT         NOT NULL,
+    event            VARCHAR(50) NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           VARCHAR(20) NOT NULL,
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP   NOT NULL,
+    last_attempt_at  TIMESTAMP,
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMP,
+    created_at       TIMESTAMP   NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_outbox_pending_idx ON webhook_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_outbox_webhook_idx ON webhook_outbox (webhook_id, id);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE tasks
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys
+(
+    key         VARCHAR(255) PRIMARY KEY,
+    fingerprint VARCHAR(64)  NOT NULL,
+    status      INT,
+    header      JSONB,
+    body        TEXT,
+    created_at  TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX idempotency_keys_created_idx ON idempotency_keys (created_at);
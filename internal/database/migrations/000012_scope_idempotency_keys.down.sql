@@ -0,0 +1,13 @@
+-- Of the keys that several clients used, only the latest is kept.
+DELETE FROM idempotency_keys a
+    USING idempotency_keys b
+    WHERE a.key = b.key AND (a.created_at, a.client) < (b.created_at, b.client);
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey;
+
+ALTER TABLE idempotency_keys
+    DROP COLUMN client;
+
+ALTER TABLE idempotency_keys
+    ADD PRIMARY KEY (key);
@@ -0,0 +1,12 @@
+-- Keys stored before the change belong to no client; they expire with the window.
+ALTER TABLE idempotency_keys
+    ADD COLUMN client VARCHAR(80) NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_keys
+    ALTER COLUMN client DROP DEFAULT;
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey;
+
+ALTER TABLE idempotency_keys
+    ADD PRIMARY KEY (client, key);
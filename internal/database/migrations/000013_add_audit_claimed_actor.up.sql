@@ -0,0 +1,4 @@
+-- actor now holds the address a change came from; the name a client gives in X-Actor
+-- is unverified and kept apart. Entries written before keep that name in actor.
+ALTER TABLE audit_log
+    ADD COLUMN claimed_actor VARCHAR(100);
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time-tracker/internal/models"
+	"unicode/utf8"
+)
+
+// sortKey describes the order of a paginated listing: rows are ordered by expr
+// and then by idColumn, which keeps the order stable and makes keyset paging possible.
+type sortKey struct {
+	expr     string // empty when the listing is ordered by idColumn alone
+	cast     string // SQL type the cursor value is cast to: "text", or "interval" given as "<n> microseconds"
+	idColumn string
+	desc     bool
+}
+
+// keysetCondition selects the rows that follow the cursor in the direction of travel.
+// A cursor value the database could not cast is reported as ErrInvalidCursor before
+// any query is made.
+func (k sortKey) keysetCondition(cursor *models.Cursor, argCount int) (string, []interface{}, error) {
+	op := ">"
+	if k.desc != cursor.Backward {
+		op = "<"
+	}
+	if k.expr == "" {
+		return fmt.Sprintf("%s %s $%d", k.idColumn, op, argCount), []interface{}{cursor.ID}, nil
+	}
+	if !k.validCursorValue(cursor.Value) {
+		return "", nil, ErrInvalidCursor
+	}
+	return fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", k.expr, k.idColumn, op, argCount, k.cast, argCount+1),
+		[]interface{}{cursor.Value, cursor.ID}, nil
+}
+
+func (k sortKey) validCursorValue(value string) bool {
+	switch k.cast {
+	case "interval":
+		micros := strings.TrimSuffix(value, " microseconds")
+		if micros == value {
+			return false
+		}
+		_, err := strconv.ParseInt(micros, 10, 64)
+		return err == nil
+	case "text":
+		// PostgreSQL text holds neither invalid UTF-8 nor NUL characters.
+		return utf8.ValidString(value) && !strings.ContainsRune(value, 0)
+	}
+	return false
+}
+
+// orderAndLimit returns the ORDER BY and LIMIT clauses for the page. One extra row is
+// requested so the caller can tell whether the listing continues past this page.
+func (k sortKey) orderAndLimit(page models.PageRequest) string {
+	desc := k.desc
+	if page.Cursor != nil && page.Cursor.Backward {
+		desc = !desc
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	clause := fmt.Sprintf(" ORDER BY %s %s", k.idColumn, direction)
+	if k.expr != "" {
+		clause = fmt.Sprintf(" ORDER BY %s %s, %s %s", k.expr, direction, k.idColumn, direction)
+	}
+
+	if page.Keyset {
+		return clause + fmt.Sprintf(" LIMIT %d", page.Limit+1)
+	}
+	return clause + fmt.Sprintf(" LIMIT %d OFFSET %d", page.Limit+1, page.Offset)
+}
+
+// trimPage drops the lookahead row and restores the natural order of a backward page.
+// It reports whether more rows exist in the direction of travel.
+func trimPage[T any](items []T, page models.PageRequest) ([]T, bool) {
+	more := len(items) > page.Limit
+	if more {
+		items = items[:page.Limit]
+	}
+	if page.Cursor != nil && page.Cursor.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	return items, more
+}
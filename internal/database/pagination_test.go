@@ -0,0 +1,37 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time-tracker/internal/models"
+)
+
+func TestKeysetConditionRejectsMalformedValues(t *testing.T) {
+	duration := sortKey{expr: "duration", cast: "interval", idColumn: "id"}
+	surname := sortKey{expr: "surname", cast: "text", idColumn: "id"}
+
+	tests := []struct {
+		key   sortKey
+		value string
+		valid bool
+	}{
+		{duration, "5400000000 microseconds", true},
+		{duration, "-1 microseconds", true},
+		{duration, "1 hour", false},
+		{duration, "1.5 microseconds", false},
+		{duration, "", false},
+		{surname, "Иванов", true},
+		{surname, "", true},
+		{surname, "a\x00b", false},
+		{surname, "\xff", false},
+	}
+	for _, tt := range tests {
+		_, _, err := tt.key.keysetCondition(&models.Cursor{Value: tt.value, ID: 1}, 1)
+		if tt.valid && err != nil {
+			t.Errorf("%s cursor %q: %v", tt.key.cast, tt.value, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s cursor %q: got %v, want ErrInvalidCursor", tt.key.cast, tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
+)
+
+// sealUser returns a copy of the user with the passport number and address encrypted.
+func sealUser(user models.User) (models.User, error) {
+	var err error
+	user.PassportNumber, err = pii.Encrypt(user.PassportNumber)
+	if err != nil {
+		return user, fmt.Errorf("failed to encrypt passport number: %v", err)
+	}
+	user.Address, err = pii.Encrypt(user.Address)
+	if err != nil {
+		return user, fmt.Errorf("failed to encrypt address: %v", err)
+	}
+	return user, nil
+}
+
+// openUser decrypts the passport number and address of a user read from the database.
+func openUser(user *models.User) error {
+	var err error
+	user.PassportNumber, err = pii.Decrypt(user.PassportNumber)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt passport number of user %d: %v", user.ID, err)
+	}
+	user.Address, err = pii.Decrypt(user.Address)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt address of user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// openUserSnapshot decrypts a user snapshot stored in the audit log. The audit log is
+// append-only, so snapshots written before encryption was enabled stay in plaintext and
+// values sealed with a removed key cannot be opened; such values are returned as stored.
+// For the same reason the snapshots of an anonymized user cannot be scrubbed, so they are
+// redacted to what the users table holds after the erasure.
+func openUserSnapshot(snapshot []byte, anonymized bool) ([]byte, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	var user models.User
+	if err := json.Unmarshal(snapshot, &user); err != nil {
+		return nil, err
+	}
+	if anonymized {
+		user = models.User{ID: user.ID, Surname: anonymizedSurname, Name: anonymizedName, Team: user.Team, Version: user.Version}
+		return json.Marshal(user)
+	}
+	if passport, err := pii.Decrypt(user.PassportNumber); err == nil {
+		user.PassportNumber = passport
+	}
+	if address, err := pii.Decrypt(user.Address); err == nil {
+		user.Address = address
+	}
+	return json.Marshal(user)
+}
+
+// ReencryptUsers encrypts personal data stored before encryption was enabled and
+// re-encrypts values sealed with a key that is no longer current, so old keys can be
+// removed after a rotation. It returns the number of updated users.
+func ReencryptUsers() (int, error) {
+	logger.Logger.Info("Re-encrypting users")
+	defer logger.Logger.Info("Done re-encrypting users")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, passport_number, address, passport_hash IS NOT NULL, anonymized_at IS NOT NULL
+							FROM users FOR UPDATE`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read users: %v", err)
+	}
+
+	type staleUser struct {
+		user       models.User
+		anonymized bool
+	}
+	var stale []staleUser
+	for rows.Next() {
+		var user models.User
+		var hashed, anonymized bool
+		if err := rows.Scan(&user.ID, &user.PassportNumber, &user.Address, &hashed, &anonymized); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user: %v", err)
+		}
+		// Rows without blind indexes hold plaintext, unless the user was anonymized.
+		encrypted := hashed || anonymized
+		if encrypted && pii.IsCurrent(user.PassportNumber) && pii.IsCurrent(user.Address) {
+			continue
+		}
+		if encrypted {
+			if err := openUser(&user); err != nil {
+				rows.Close()
+				return 0, err
+			}
+		}
+		stale = append(stale, staleUser{user: user, anonymized: anonymized})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read users: %v", err)
+	}
+
+	for _, s := range stale {
+		sealed, err := sealUser(s.user)
+		if err != nil {
+			return 0, err
+		}
+		var passportHash, addressHash interface{}
+		if !s.anonymized {
+			passportHash, addressHash = pii.BlindIndex(s.user.PassportNumber), pii.BlindIndex(s.user.Address)
+		}
+		query := `UPDATE users SET passport_number = $1, address = $2, passport_hash = $3, address_hash = $4 WHERE id = $5`
+		_, err = tx.Exec(query, sealed.PassportNumber, sealed.Address, passportHash, addressHash, s.user.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt user %d: %v", s.user.ID, err)
+		}
+	}
+
+	return len(stale), tx.Commit()
+}
+
+// sealedColumns are the columns other than the users' personal data that are encrypted
+// with the same keys, by table, with the columns that identify a row.
+var sealedColumns = []struct {
+	table     string
+	idColumns []string
+	column    string
+}{
+	{"webhooks", []string{"id"}, "secret"},
+	{"idempotency_keys", []string{"client", "key"}, "body"},
+}
+
+// ReencryptSecrets re-encrypts the webhook secrets and the stored idempotent responses
+// sealed with a key that is no longer current, so old keys can be removed after a
+// rotation. It returns the number of updated rows.
+func ReencryptSecrets() (int, error) {
+	logger.Logger.Info("Re-encrypting secrets")
+	defer logger.Logger.Info("Done re-encrypting secrets")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, c := range sealedColumns {
+		count, err := reencryptColumn(tx, c.table, c.idColumns, c.column)
+		if err != nil {
+			return 0, err
+		}
+		updated += count
+	}
+	return updated, tx.Commit()
+}
+
+func reencryptColumn(tx *sql.Tx, table string, idColumns []string, column string) (int, error) {
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE %s IS NOT NULL FOR UPDATE`,
+		strings.Join(idColumns, ", "), column, table, column)
+	rows, err := tx.Query(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", table, err)
+	}
+
+	type sealedValue struct {
+		id    []interface{}
+		value string
+	}
+	var stale []sealedValue
+	for rows.Next() {
+		ids := make([]string, len(idColumns))
+		dest := make([]interface{}, 0, len(ids)+1)
+		for i := range ids {
+			dest = append(dest, &ids[i])
+		}
+		var v sealedValue
+		if err := rows.Scan(append(dest, &v.value)...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s: %v", table, err)
+		}
+		if !pii.IsCurrent(v.value) {
+			for _, id := range ids {
+				v.id = append(v.id, id)
+			}
+			stale = append(stale, v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", table, err)
+	}
+
+	conditions := make([]string, len(idColumns))
+	for i, idColumn := range idColumns {
+		conditions[i] = fmt.Sprintf("%s = $%d", idColumn, i+2)
+	}
+	query = fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s`, table, column, strings.Join(conditions, " AND "))
+	for _, v := range stale {
+		plaintext, err := pii.Decrypt(v.value)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt %s.%s of %v: %v", table, column, v.id, err)
+		}
+		sealed, err := pii.Encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(query, append([]interface{}{sealed}, v.id...)...); err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt %s.%s of %v: %v", table, column, v.id, err)
+		}
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+var ErrUserAnonymized = errors.New("user is already anonymized")
+
+const (
+	anonymizedSurname = "Anonymized"
+	anonymizedName    = "User"
+)
+
+// GetAllTasks returns every time entry of a user, including running timers.
+func GetAllTasks(userId int) ([]models.Task, error) {
+	logger.Logger.Info("Getting all tasks")
+	defer logger.Logger.Info("Done getting all tasks")
+
+	query := `SELECT user_id, task_id, title, description, start_time, end_time
+			  FROM tasks
+			  WHERE user_id = $1
+			  ORDER BY start_time`
+
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		var endTime sql.NullTime
+		if err := rows.Scan(&task.UserID, &task.TaskID, &task.Title, &task.Description, &task.StartTime, &endTime); err != nil {
+			return nil, err
+		}
+		task.EndTime = endTime.Time
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// AnonymizeUser scrubs the personal data of a user while keeping the user's time
+// entries, so they still count in aggregate reports. The passport and address blind
+// indexes are cleared, which frees the passport number for a new registration. A
+// non-zero version makes the erasure conditional, as in StopTaskTimer. Running timers
+// of the user are stopped, as nobody tracks time for an erased user any more.
+func AnonymizeUser(actor models.Actor, userId, version int) error {
+	logger.Logger.Info("Anonymizing user")
+	defer logger.Logger.Info("Done anonymizing user")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockActiveUser(tx, userId); err != nil {
+		return err
+	}
+
+	scrubbed, err := sealUser(models.User{})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	timers, err := lockRunningTimers(tx, userId)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+			  SET surname = $1, name = $2, patronymic = NULL, passport_number = $3, address = $4,
+			      passport_hash = NULL, address_hash = NULL, anonymized_at = $5, version = version + 1
+			  WHERE id = $6 AND ($7 = 0 OR version = $7)`
+	result, err := tx.Exec(query, anonymizedSurname, anonymizedName, scrubbed.PassportNumber, scrubbed.Address, now, userId, version)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+	if err := expectChanged(result); err != nil {
+		return err
+	}
+
+	for i, timer := range timers {
+		before, err := getTask(tx, timer.TaskID)
+		if err != nil {
+			return err
+		}
+		if _, err := closeTimer(tx, actor, before, now); err != nil {
+			return err
+		}
+		end := now
+		timers[i].EndTime = &end
+	}
+
+	// The calendar feed carries task names, so an anonymized user no longer publishes one.
+	_, err = tx.Exec(`DELETE FROM calendar_tokens WHERE user_id = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke calendar token: %v", err)
+	}
+
+	// Webhook payloads carry the name of the user and stay readable through the delivery
+	// log, so the queued and delivered ones are scrubbed as well.
+	err = redactWebhookPayloads(tx, userId)
+	if err != nil {
+		return err
+	}
+
+	// The entry deliberately holds no snapshot: copying the data being erased into the
+	// audit log would defeat the erasure.
+	err = saveAuditEntry(tx, actor, models.AuditUserAnonymize, models.AuditTargetUser, userId, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhookEvent(tx, models.WebhookUserAnonymized, models.WebhookUser{ID: userId, Surname: anonymizedSurname, Name: anonymizedName})
+	if err != nil {
+		return err
+	}
+
+	err = notifyTimersStopped(tx, timers, now)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockActiveUser locks the user row for the rest of the transaction. It returns
+// ErrUserAnonymized for an anonymized user, whose personal data must not be written
+// again.
+func lockActiveUser(tx *sql.Tx, userId int) error {
+	var anonymizedAt sql.NullTime
+	err := tx.QueryRow(`SELECT anonymized_at FROM users WHERE id = $1 FOR UPDATE`, userId).Scan(&anonymizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if anonymizedAt.Valid {
+		return ErrUserAnonymized
+	}
+	return nil
+}
+
+// redactWebhookPayloads replaces the name of the user in the payloads of user events in
+// the webhook outbox with the anonymized one.
+func redactWebhookPayloads(tx *sql.Tx, userId int) error {
+	query := `UPDATE webhook_outbox
+			  SET payload = jsonb_set(payload, '{data}', (payload->'data') ||
+			      jsonb_build_object('surname', $1::text, 'name', $2::text, 'patronymic', ''))
+			  WHERE event IN ($3, $4) AND payload->'data'->>'id' = $5::text`
+	_, err := tx.Exec(query, anonymizedSurname, anonymizedName, models.WebhookUserCreated, models.WebhookUserUpdated, userId)
+	if err != nil {
+		return fmt.Errorf("failed to redact webhook payloads: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// searchClauses builds the match condition and rank expression for a set of query
+// variants: a row matches when any variant is similar to its search_text (trigrams)
+// or matches its search_vector (full-text), and ranks by the best of these scores.
+func searchClauses(variants []string) (string, string, []interface{}) {
+	var conditions, ranks []string
+	var args []interface{}
+	for i, variant := range variants {
+		n := i + 1
+		conditions = append(conditions, fmt.Sprintf(
+			"search_text %% $%[1]d OR $%[1]d <%% search_text OR search_vector @@ plainto_tsquery('simple', $%[1]d)", n))
+		ranks = append(ranks, fmt.Sprintf(
+			"similarity(search_text, $%[1]d), word_similarity($%[1]d, search_text), ts_rank(search_vector, plainto_tsquery('simple', $%[1]d))", n))
+		args = append(args, variant)
+	}
+	return strings.Join(conditions, " OR "), "GREATEST(" + strings.Join(ranks, ", ") + ")", args
+}
+
+func SearchUsers(variants []string, limit int) ([]models.UserSearchResult, error) {
+	logger.Logger.Info("Searching users")
+	defer logger.Logger.Info("Done searching users")
+
+	condition, rank, args := searchClauses(variants)
+	query := fmt.Sprintf(`SELECT id, surname, name, patronymic, passport_number, address, team, %s AS rank
+			  FROM users
+			  WHERE %s
+			  ORDER BY rank DESC, id
+			  LIMIT %d`, rank, condition, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %v", err)
+	}
+	defer rows.Close()
+
+	results := []models.UserSearchResult{}
+	for rows.Next() {
+		var result models.UserSearchResult
+		var patronymic, team sql.NullString
+		err = rows.Scan(&result.ID, &result.Surname, &result.Name, &patronymic, &result.PassportNumber, &result.Address, &team, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		result.Patronymic = patronymic.String
+		result.Team = team.String
+		if err := openUser(&result.User); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search users: %v", err)
+	}
+	return results, nil
+}
+
+func SearchTasks(variants []string, limit int) ([]models.TaskSearchResult, error) {
+	logger.Logger.Info("Searching tasks")
+	defer logger.Logger.Info("Done searching tasks")
+
+	condition, rank, args := searchClauses(variants)
+	query := fmt.Sprintf(`SELECT user_id, task_id, title, description, start_time, end_time, %s AS rank
+			  FROM tasks
+			  WHERE %s
+			  ORDER BY rank DESC, task_id
+			  LIMIT %d`, rank, condition, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tasks: %v", err)
+	}
+	defer rows.Close()
+
+	results := []models.TaskSearchResult{}
+	for rows.Next() {
+		var result models.TaskSearchResult
+		var endTime sql.NullTime
+		err = rows.Scan(&result.UserID, &result.TaskID, &result.Title, &result.Description, &result.StartTime, &endTime, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %v", err)
+		}
+		result.EndTime = endTime.Time
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search tasks: %v", err)
+	}
+	return results, nil
+}
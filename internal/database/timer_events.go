@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// timerEventsChannel is the PostgreSQL notification channel of timers ended as a side
+// effect of another change: removed with their user, stopped by anonymization or closed
+// by the admin command, which runs in a process of its own. The server relays these
+// notifications to its live update streams; timers started and stopped through the API
+// are published by the service layer directly.
+const timerEventsChannel = "timer_events"
+
+// dsn is kept from Connect for the dedicated connection of the notification listener.
+var dsn string
+
+// notifyTimersStopped queues a timer.stopped notification for each timer. PostgreSQL
+// delivers the notifications when the transaction commits and drops them on rollback.
+// Timers without an end time are reported as ended at the time of the event.
+func notifyTimersStopped(tx *sql.Tx, timers []models.Timer, at time.Time) error {
+	for _, timer := range timers {
+		if timer.EndTime == nil {
+			end := at
+			timer.EndTime = &end
+		}
+		payload, err := json.Marshal(models.NewTimerEvent(models.TimerStopped, timer, at))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, timerEventsChannel, string(payload)); err != nil {
+			return fmt.Errorf("failed to notify timer stop: %v", err)
+		}
+	}
+	return nil
+}
+
+// lockRunningTimers returns the running timers of a user and locks their rows until
+// the end of the transaction.
+func lockRunningTimers(tx *sql.Tx, userId int) ([]models.Timer, error) {
+	query := `SELECT t.task_id, t.user_id, u.team, t.title, t.start_time, t.end_time
+			  FROM tasks t JOIN users u ON u.id = t.user_id
+			  WHERE t.user_id = $1 AND t.end_time IS NULL
+			  ORDER BY t.start_time, t.task_id
+			  FOR UPDATE OF t`
+	rows, err := tx.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve running timers: %v", err)
+	}
+	defer rows.Close()
+
+	var timers []models.Timer
+	for rows.Next() {
+		timer, err := scanTimer(rows)
+		if err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve running timers: %v", err)
+	}
+	return timers, nil
+}
+
+// ListenTimerEvents passes the timer events notified through the database to handle
+// until the context is cancelled. The listener reconnects by itself; events notified
+// while it is disconnected are lost.
+func ListenTimerEvents(ctx context.Context, handle func(models.TimerEvent)) error {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Logger.Warn("Timer event listener connection problem", zap.Error(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(timerEventsChannel); err != nil {
+		return fmt.Errorf("failed to listen for timer events: %v", err)
+	}
+
+	// A ping now and then notices a connection that died without an error.
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// A nil notification marks a reconnection.
+			if notification == nil {
+				continue
+			}
+			var event models.TimerEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				logger.Logger.Error("Invalid timer event notification", zap.Error(err))
+				continue
+			}
+			handle(event)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
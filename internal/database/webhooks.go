@@ -0,0 +1,465 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// enqueueWebhookEvent writes an event to the outbox of every active webhook
+// subscribed to it. It runs in the transaction of the change, so an event is queued
+// exactly when the change is committed.
+func enqueueWebhookEvent(tx *sql.Tx, event string, data interface{}) error {
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{Event: event, OccurredAt: now, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	query := `INSERT INTO webhook_outbox (webhook_id, event, payload, status, next_attempt_at, created_at)
+			  SELECT id, $1, $2, $3, $4, $4 FROM webhooks
+			  WHERE active AND (events = '[]' OR events @> jsonb_build_array($1::text))`
+	_, err = tx.Exec(query, event, string(payload), models.DeliveryPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook event: %v", err)
+	}
+	return nil
+}
+
+// CreateWebhook stores a webhook and returns it with its signing secret. A secret is
+// generated when the request has none.
+func CreateWebhook(actor models.Actor, req models.WebhookRequest) (models.Webhook, string, error) {
+	logger.Logger.Info("Creating webhook")
+	defer logger.Logger.Info("Done creating webhook")
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	} else {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return models.Webhook{}, "", fmt.Errorf("failed to generate webhook secret: %v", err)
+		}
+		secret = hex.EncodeToString(raw)
+	}
+	sealed, err := pii.Encrypt(secret)
+	if err != nil {
+		return models.Webhook{}, "", fmt.Errorf("failed to encrypt webhook secret: %v", err)
+	}
+
+	webhook := models.Webhook{URL: *req.URL, Events: []string{}, Active: true}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return models.Webhook{}, "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Webhook{}, "", err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO webhooks (url, secret, events, active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err = tx.QueryRow(query, webhook.URL, sealed, string(events), webhook.Active, webhook.CreatedAt, webhook.UpdatedAt).
+		Scan(&webhook.ID)
+	if err != nil {
+		return models.Webhook{}, "", fmt.Errorf("failed to save webhook: %v", err)
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditWebhookCreate, models.AuditTargetWebhook, webhook.ID, nil, webhook)
+	if err != nil {
+		return models.Webhook{}, "", err
+	}
+	return webhook, secret, tx.Commit()
+}
+
+func GetWebhooks() ([]models.Webhook, error) {
+	logger.Logger.Info("Getting webhooks")
+	defer logger.Logger.Info("Done getting webhooks")
+
+	rows, err := db.Query(`SELECT id, url, events, active, created_at, updated_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhooks: %v", err)
+	}
+	return webhooks, nil
+}
+
+func GetWebhook(webhookId int) (models.Webhook, error) {
+	logger.Logger.Info("Getting webhook")
+	defer logger.Logger.Info("Done getting webhook")
+
+	return getWebhook(db, webhookId)
+}
+
+func getWebhook(q querier, webhookId int) (models.Webhook, error) {
+	row := q.QueryRow(`SELECT id, url, events, active, created_at, updated_at FROM webhooks WHERE id = $1`, webhookId)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events []byte
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhook, err
+		}
+		return webhook, fmt.Errorf("failed to scan webhook: %v", err)
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return webhook, fmt.Errorf("failed to read events of webhook %d: %v", webhook.ID, err)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook changes the fields present in the request and returns the webhook.
+func UpdateWebhook(actor models.Actor, webhookId int, req models.WebhookRequest) (models.Webhook, error) {
+	logger.Logger.Info("Updating webhook")
+	defer logger.Logger.Info("Done updating webhook")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := getWebhook(tx, webhookId)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	after := before
+	if req.URL != nil {
+		after.URL = *req.URL
+	}
+	if req.Events != nil {
+		after.Events = *req.Events
+	}
+	if req.Active != nil {
+		after.Active = *req.Active
+	}
+	after.UpdatedAt = time.Now()
+
+	events, err := json.Marshal(after.Events)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	query := `UPDATE webhooks SET url = $1, events = $2, active = $3, updated_at = $4 WHERE id = $5`
+	_, err = tx.Exec(query, after.URL, string(events), after.Active, after.UpdatedAt, webhookId)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to update webhook: %v", err)
+	}
+
+	if req.Secret != nil {
+		sealed, err := pii.Encrypt(*req.Secret)
+		if err != nil {
+			return models.Webhook{}, fmt.Errorf("failed to encrypt webhook secret: %v", err)
+		}
+		_, err = tx.Exec(`UPDATE webhooks SET secret = $1 WHERE id = $2`, sealed, webhookId)
+		if err != nil {
+			return models.Webhook{}, fmt.Errorf("failed to update webhook secret: %v", err)
+		}
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditWebhookUpdate, models.AuditTargetWebhook, webhookId, before, after)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return after, tx.Commit()
+}
+
+// DeleteWebhook removes a webhook together with its queued and past deliveries.
+func DeleteWebhook(actor models.Actor, webhookId int) error {
+	logger.Logger.Info("Deleting webhook")
+	defer logger.Logger.Info("Done deleting webhook")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getWebhook(tx, webhookId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM webhooks WHERE id = $1`, webhookId)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+
+	err = saveAuditEntry(tx, actor, models.AuditWebhookDelete, models.AuditTargetWebhook, webhookId, before, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PendingDelivery is a delivery claimed by the worker, with everything needed to send it.
+type PendingDelivery struct {
+	ID        int
+	WebhookID int
+	Event     string
+	URL       string
+	Secret    string
+	Payload   []byte
+	Attempts  int
+}
+
+// ClaimWebhookDeliveries locks up to limit due deliveries and pushes their next attempt
+// back by lease, so another worker does not pick them up while they are being sent. A
+// delivery whose worker dies is retried once the lease runs out.
+func ClaimWebhookDeliveries(limit int, lease time.Duration) ([]PendingDelivery, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `SELECT o.id, o.webhook_id, o.event, w.url, w.secret, o.payload, o.attempts
+			  FROM webhook_outbox o JOIN webhooks w ON w.id = o.webhook_id
+			  WHERE o.status = $1 AND o.next_attempt_at <= $2 AND w.active
+			  ORDER BY o.next_attempt_at, o.id
+			  LIMIT $3
+			  FOR UPDATE OF o SKIP LOCKED`
+	rows, err := tx.Query(query, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %v", err)
+	}
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var delivery PendingDelivery
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.URL, &delivery.Secret,
+			&delivery.Payload, &delivery.Attempts)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %v", err)
+	}
+
+	// A secret that cannot be decrypted, such as one sealed with a removed key, will not
+	// open on a later attempt either, so its delivery fails instead of blocking the queue.
+	claimed := deliveries[:0]
+	for _, delivery := range deliveries {
+		delivery.Secret, err = pii.Decrypt(delivery.Secret)
+		if err != nil {
+			query := `UPDATE webhook_outbox
+					  SET status = $1, attempts = attempts + 1, last_attempt_at = $2, last_error = $3
+					  WHERE id = $4`
+			_, err = tx.Exec(query, models.DeliveryFailed, now, fmt.Sprintf("failed to decrypt webhook secret: %v", err), delivery.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record webhook delivery failure: %v", err)
+			}
+			continue
+		}
+		_, err = tx.Exec(`UPDATE webhook_outbox SET next_attempt_at = $1 WHERE id = $2`, now.Add(lease), delivery.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease webhook delivery: %v", err)
+		}
+		claimed = append(claimed, delivery)
+	}
+	return claimed, tx.Commit()
+}
+
+// CompleteWebhookDelivery records a successful delivery attempt.
+func CompleteWebhookDelivery(deliveryId, statusCode int) error {
+	now := time.Now()
+	query := `UPDATE webhook_outbox
+			  SET status = $1, attempts = attempts + 1, last_attempt_at = $2, last_status_code = $3,
+			      last_error = NULL, delivered_at = $2
+			  WHERE id = $4`
+	_, err := db.Exec(query, models.DeliveryDelivered, now, statusCode, deliveryId)
+	if err != nil {
+		return fmt.Errorf("failed to complete webhook delivery: %v", err)
+	}
+	return nil
+}
+
+// FailWebhookDelivery records a failed delivery attempt. The delivery is retried at
+// nextAttempt, or marked as failed when nextAttempt is zero.
+func FailWebhookDelivery(deliveryId, statusCode int, message string, nextAttempt time.Time) error {
+	status := models.DeliveryPending
+	next := sql.NullTime{Time: nextAttempt, Valid: !nextAttempt.IsZero()}
+	if !next.Valid {
+		status = models.DeliveryFailed
+	}
+	code := sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0}
+
+	query := `UPDATE webhook_outbox
+			  SET status = $1, attempts = attempts + 1, last_attempt_at = $2, last_status_code = $3,
+			      last_error = $4, next_attempt_at = COALESCE($5, next_attempt_at)
+			  WHERE id = $6`
+	_, err := db.Exec(query, status, time.Now(), code, message, next, deliveryId)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery failure: %v", err)
+	}
+	return nil
+}
+
+// PurgeWebhookDeliveries deletes the delivered and failed deliveries last attempted
+// before expiredBefore and returns how many there were. Pending deliveries are kept
+// until they end.
+func PurgeWebhookDeliveries(expiredBefore time.Time) (int64, error) {
+	query := `DELETE FROM webhook_outbox
+			  WHERE status <> $1 AND COALESCE(last_attempt_at, created_at) < $2`
+	result, err := db.Exec(query, models.DeliveryPending, expiredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// RetryWebhookDelivery queues a delivery of the webhook for an immediate new attempt.
+func RetryWebhookDelivery(webhookId, deliveryId int) (models.WebhookDelivery, error) {
+	logger.Logger.Info("Retrying webhook delivery")
+	defer logger.Logger.Info("Done retrying webhook delivery")
+
+	query := `UPDATE webhook_outbox SET status = $1, next_attempt_at = $2
+			  WHERE id = $3 AND webhook_id = $4
+			  RETURNING ` + deliveryColumns
+	row := db.QueryRow(query, models.DeliveryPending, time.Now(), deliveryId, webhookId)
+	delivery, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	return delivery, err
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at,
+			  last_status_code, last_error, delivered_at, created_at`
+
+func GetWebhookDeliveries(filter models.DeliveryFilter, page models.PageRequest) ([]models.WebhookDelivery, bool, error) {
+	logger.Logger.Info("Getting webhook deliveries")
+	defer logger.Logger.Info("Done getting webhook deliveries")
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_outbox WHERE webhook_id = $1`
+	args := []interface{}{filter.WebhookID}
+	if filter.Status != "" {
+		query += ` AND status = $2`
+		args = append(args, filter.Status)
+	}
+
+	key := sortKey{idColumn: "id", desc: true}
+	if page.Cursor != nil {
+		condition, cursorArgs, err := key.keysetCondition(page.Cursor, len(args)+1)
+		if err != nil {
+			return nil, false, err
+		}
+		query += " AND " + condition
+		args = append(args, cursorArgs...)
+	}
+	query += key.orderAndLimit(page)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve webhook deliveries: %v", err)
+	}
+
+	deliveries, more := trimPage(deliveries, page)
+	return deliveries, more, nil
+}
+
+func CountWebhookDeliveries(filter models.DeliveryFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM webhook_outbox WHERE webhook_id = $1`
+	args := []interface{}{filter.WebhookID}
+	if filter.Status != "" {
+		query += ` AND status = $2`
+		args = append(args, filter.Status)
+	}
+
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count webhook deliveries: %v", err)
+	}
+	return count, nil
+}
+
+func scanDelivery(row scanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	var nextAttempt, lastAttempt, deliveredAt sql.NullTime
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts,
+		&nextAttempt, &lastAttempt, &statusCode, &lastError, &deliveredAt, &delivery.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return delivery, err
+		}
+		return delivery, fmt.Errorf("failed to scan webhook delivery: %v", err)
+	}
+
+	delivery.Payload = payload
+	if nextAttempt.Valid && delivery.Status == models.DeliveryPending {
+		delivery.NextAttemptAt = &nextAttempt.Time
+	}
+	if lastAttempt.Valid {
+		delivery.LastAttemptAt = &lastAttempt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	delivery.LastStatusCode = int(statusCode.Int64)
+	delivery.LastError = lastError.String
+	return delivery, nil
+}
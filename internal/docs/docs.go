@@ -0,0 +1,62 @@
+// Package docs holds the OpenAPI description of the HTTP API and the page that renders it.
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// UI is a self-contained page that loads /openapi.json and renders it, so the
+// documentation works without access to a CDN.
+//
+//go:embed index.html
+var UI []byte
+
+type spec struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// patternParam matches chi path parameters with a regexp, such as {source:toggl|harvest}.
+var patternParam = regexp.MustCompile(`\{([^{}:]+):[^{}]*\}`)
+
+// Undocumented walks the router and returns the routes, as "METHOD /path", that have no
+// operation in the spec.
+func Undocumented(routes chi.Routes) ([]string, error) {
+	var s spec
+	if err := json.Unmarshal(Spec, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %v", err)
+	}
+
+	var missing []string
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		path := specPath(route)
+		if _, ok := s.Paths[path][strings.ToLower(method)]; !ok {
+			missing = append(missing, method+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk routes: %v", err)
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// specPath turns a chi route into an OpenAPI path: regexps are dropped from
+// parameters and the trailing slash of subrouter roots is removed.
+func specPath(route string) string {
+	path := patternParam.ReplaceAllString(route, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
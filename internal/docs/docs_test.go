@@ -0,0 +1,17 @@
+package docs_test
+
+import (
+	"testing"
+	"time-tracker/internal/app"
+	"time-tracker/internal/docs"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	undocumented, err := docs.Undocumented(app.NewRouter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range undocumented {
+		t.Errorf("%s is not documented in openapi.json", route)
+	}
+}
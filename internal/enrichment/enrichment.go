@@ -0,0 +1,59 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time-tracker/internal/models"
+	"time-tracker/internal/ratelimit"
+	"time-tracker/internal/validation"
+)
+
+// apiLimiter caps the lookups made by all clients together, so the external API's own
+// quota is not used up. It is unlimited until SetRateLimit is called.
+var apiLimiter = ratelimit.New(ratelimit.Rate{})
+
+// SetRateLimit limits how often Lookup may call the external API.
+func SetRateLimit(r ratelimit.Rate) {
+	apiLimiter = ratelimit.New(r)
+}
+
+// Lookup fetches the personal data of a passport holder from the external API
+// configured in API_URL. The passport number must be normalized.
+// When the rate limit is reached it returns a *ratelimit.ExceededError without calling
+// the API.
+func Lookup(passport string) (models.User, error) {
+	if err := apiLimiter.Allow(""); err != nil {
+		return models.User{}, err
+	}
+	series, number := validation.SplitPassport(passport)
+	params := url.Values{}
+	params.Add("passportSerie", series)
+	params.Add("passportNumber", number)
+
+	resp, err := http.Get(fmt.Sprintf("%s?%s", os.Getenv("API_URL"), params.Encode()))
+	// The request URL carries the passport number, keep it out of logs and responses.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to get user info from external API: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.User{}, fmt.Errorf("failed to get user info from external API: status %d", resp.StatusCode)
+	}
+
+	user := models.User{PassportNumber: passport}
+	err = json.NewDecoder(resp.Body).Decode(&user)
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed parsing user info from external API: %v", err)
+	}
+	// The API must not change the passport the data was requested for.
+	user.PassportNumber = passport
+	return user, nil
+}
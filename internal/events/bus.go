@@ -0,0 +1,56 @@
+// Package events is an in-process publish/subscribe bus for timer changes. It feeds
+// the live update streams of this instance only; other consumers use webhooks.
+package events
+
+import (
+	"go.uber.org/zap"
+	"sync"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before events to it
+// are dropped. Publishing never blocks on a slow subscriber.
+const subscriberBuffer = 64
+
+type Subscription struct {
+	C  <-chan models.TimerEvent
+	ch chan models.TimerEvent
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[*Subscription]struct{}{}
+)
+
+// Subscribe registers a subscriber for all timer events. The subscription must be
+// cancelled with Unsubscribe.
+func Subscribe() *Subscription {
+	ch := make(chan models.TimerEvent, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch}
+
+	mu.Lock()
+	subscribers[sub] = struct{}{}
+	mu.Unlock()
+	return sub
+}
+
+func Unsubscribe(sub *Subscription) {
+	mu.Lock()
+	delete(subscribers, sub)
+	mu.Unlock()
+}
+
+func Publish(event models.TimerEvent) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for sub := range subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			logger.Logger.Warn("Timer event dropped for slow subscriber",
+				zap.String("type", event.Type), zap.Int("taskId", event.TaskID))
+		}
+	}
+}
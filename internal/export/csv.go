@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingWindows1251 = "windows-1251"
+)
+
+// CSVOptions controls the dialect of a CSV export. Windows-1251 is what older
+// Excel versions expect when opening a Russian CSV file by double click.
+type CSVOptions struct {
+	Delimiter rune
+	Encoding  string
+}
+
+// ParseCSVOptions reads the delimiter ("," by default, ";" or "tab") and the
+// encoding ("utf-8" by default or "windows-1251") from request parameters.
+func ParseCSVOptions(delimiter, enc string) (CSVOptions, error) {
+	options := CSVOptions{Delimiter: ',', Encoding: EncodingUTF8}
+
+	switch strings.ToLower(delimiter) {
+	case "", ",", "comma":
+	case ";", "semicolon":
+		options.Delimiter = ';'
+	case "\t", "tab":
+		options.Delimiter = '\t'
+	default:
+		return options, fmt.Errorf("unsupported delimiter: %s", delimiter)
+	}
+
+	switch strings.ToLower(enc) {
+	case "", "utf-8", "utf8":
+	case "windows-1251", "cp1251":
+		options.Encoding = EncodingWindows1251
+	default:
+		return options, fmt.Errorf("unsupported encoding: %s", enc)
+	}
+	return options, nil
+}
+
+func WriteCSV(w io.Writer, table Table, options CSVOptions) error {
+	if options.Encoding == EncodingWindows1251 {
+		w = encoding.ReplaceUnsupported(charmap.Windows1251.NewEncoder()).Writer(w)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = options.Delimiter
+
+	if err := writer.Write(table.Columns); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatSpreadsheetCell(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
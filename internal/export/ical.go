@@ -0,0 +1,95 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"time-tracker/internal/models"
+)
+
+const icalTimeLayout = "20060102T150405Z"
+
+// Calendar is an iCalendar (RFC 5545) feed of a user's time entries.
+type Calendar struct {
+	Name  string
+	Tasks []models.Task
+	// Now is the feed timestamp; running timers (zero EndTime) end at Now.
+	Now time.Time
+}
+
+// WriteCalendar writes the calendar as an .ics document with one VEVENT per entry.
+func WriteCalendar(w io.Writer, calendar Calendar) error {
+	bw := bufio.NewWriter(w)
+	stamp := calendar.Now.UTC().Format(icalTimeLayout)
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//time-tracker//time entries//EN")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	writeICalLine(bw, "METHOD:PUBLISH")
+	writeICalLine(bw, "X-WR-CALNAME:"+escapeICalText(calendar.Name))
+
+	for _, task := range calendar.Tasks {
+		start := models.WallClock(task.StartTime)
+		end := models.WallClock(task.EndTime)
+		running := task.EndTime.IsZero()
+		if running {
+			end = calendar.Now
+		}
+
+		summary := task.Title
+		if summary == "" {
+			summary = fmt.Sprintf("Task #%d", task.TaskID)
+		}
+		if running {
+			summary += " (running)"
+		}
+		description := fmt.Sprintf("Duration: %s", formatDuration(end.Sub(start)))
+		if task.Description != "" {
+			description = task.Description + "\n" + description
+		}
+
+		writeICalLine(bw, "BEGIN:VEVENT")
+		writeICalLine(bw, fmt.Sprintf("UID:task-%d@time-tracker", task.TaskID))
+		writeICalLine(bw, "DTSTAMP:"+stamp)
+		writeICalLine(bw, "DTSTART:"+start.UTC().Format(icalTimeLayout))
+		writeICalLine(bw, "DTEND:"+end.UTC().Format(icalTimeLayout))
+		writeICalLine(bw, "SUMMARY:"+escapeICalText(summary))
+		writeICalLine(bw, "DESCRIPTION:"+escapeICalText(description))
+		writeICalLine(bw, "TRANSP:TRANSPARENT")
+		if running {
+			writeICalLine(bw, "STATUS:TENTATIVE")
+		} else {
+			writeICalLine(bw, "STATUS:CONFIRMED")
+		}
+		writeICalLine(bw, "END:VEVENT")
+	}
+
+	writeICalLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+func escapeICalText(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(s)
+}
+
+// writeICalLine writes a content line folded at 75 octets, never splitting a UTF-8
+// sequence. Continuation lines start with a space, which counts towards their length.
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
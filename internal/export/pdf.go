@@ -0,0 +1,108 @@
+package export
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+//go:embed fonts/DejaVuSans.ttf
+var fontRegular []byte
+
+//go:embed fonts/DejaVuSans-Bold.ttf
+var fontBold []byte
+
+const (
+	pdfFont      = "DejaVu"
+	pdfRowHeight = 5.5
+)
+
+// WriteTimesheetPDF renders a one-page A4 timesheet: header with the employee's full
+// name, a day-by-day table, totals and a signature block.
+func WriteTimesheetPDF(w io.Writer, timesheet Timesheet) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 12, 15)
+	pdf.SetAutoPageBreak(true, 12)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", fontRegular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", fontBold)
+	pdf.SetTitle(fmt.Sprintf("Timesheet %s %d", timesheet.Month.Month(), timesheet.Month.Year()), true)
+	pdf.AddPage()
+
+	start, end := MonthPeriod(timesheet.Month)
+
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.CellFormat(0, 9, "Timesheet", "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.CellFormat(0, 6, fmt.Sprintf("%s %d", timesheet.Month.Month(), timesheet.Month.Year()), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	user := timesheet.User
+	fullName := strings.TrimSpace(strings.Join([]string{user.Surname, user.Name, user.Patronymic}, " "))
+	headerRows := [][2]string{
+		{"Employee", fullName},
+		{"Employee ID", fmt.Sprintf("%d", user.ID)},
+		{"Team", user.Team},
+		{"Period", fmt.Sprintf("%s – %s", start.Format("02.01.2006"), end.Format("02.01.2006"))},
+	}
+	for _, row := range headerRows {
+		if row[1] == "" {
+			continue
+		}
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(35, 6, row[0]+":", "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.CellFormat(0, 6, row[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(3)
+
+	widths := []float64{40, 40, 40, 60}
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.SetFillColor(220, 220, 220)
+	for i, title := range []string{"Date", "Day", "Entries", "Hours"} {
+		pdf.CellFormat(widths[i], pdfRowHeight+1, title, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.SetFillColor(242, 242, 242)
+	for _, day := range timesheet.Days {
+		weekend := day.Date.Weekday() == time.Saturday || day.Date.Weekday() == time.Sunday
+		entries, hours := "", ""
+		if day.Entries > 0 {
+			entries = fmt.Sprintf("%d", day.Entries)
+			hours = formatDuration(day.Duration)
+		}
+		pdf.CellFormat(widths[0], pdfRowHeight, day.Date.Format("02.01.2006"), "1", 0, "C", weekend, 0, "")
+		pdf.CellFormat(widths[1], pdfRowHeight, day.Date.Weekday().String(), "1", 0, "C", weekend, 0, "")
+		pdf.CellFormat(widths[2], pdfRowHeight, entries, "1", 0, "C", weekend, 0, "")
+		pdf.CellFormat(widths[3], pdfRowHeight, hours, "1", 1, "C", weekend, 0, "")
+	}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(widths[0]+widths[1], pdfRowHeight+1, "Total", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[2], pdfRowHeight+1, fmt.Sprintf("%d", timesheet.Entries), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(widths[3], pdfRowHeight+1, fmt.Sprintf("%s (%.2f h)", formatDuration(timesheet.Total), timesheet.Total.Hours()), "1", 1, "C", false, 0, "")
+	pdf.Ln(10)
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, signer := range []string{"Employee", "Manager"} {
+		pdf.CellFormat(30, 6, signer+":", "", 0, "L", false, 0, "")
+		pdf.CellFormat(70, 6, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(15, 6, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(15, 6, "Date:", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, "", "B", 1, "L", false, 0, "")
+		pdf.Ln(6)
+	}
+
+	return pdf.Output(w)
+}
+
+// formatDuration renders a duration as hours and minutes, e.g. "7:05".
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
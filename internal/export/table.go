@@ -0,0 +1,47 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// Table is a named report table. Cell values are strings, ints, float64s or
+// time.Times, so spreadsheet formats can keep numbers numeric.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(timeLayout)
+	case nil:
+		return ""
+	}
+	return ""
+}
+
+// formatSpreadsheetCell formats a cell of a CSV or XLSX file. Text that a spreadsheet
+// would take for a formula, such as a title starting with "=", is prefixed with an
+// apostrophe so that opening the file cannot run it.
+func formatSpreadsheetCell(value interface{}) string {
+	text := formatCell(value)
+	if _, ok := value.(string); ok && text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
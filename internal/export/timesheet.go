@@ -0,0 +1,79 @@
+package export
+
+import (
+	"time"
+	"time-tracker/internal/models"
+)
+
+type TimesheetDay struct {
+	Date     time.Time
+	Entries  int
+	Duration time.Duration
+}
+
+// Timesheet is a monthly summary of a user's completed time entries, one row per
+// calendar day. Entries are attributed to the day they started.
+type Timesheet struct {
+	User    models.User
+	Month   time.Time
+	Days    []TimesheetDay
+	Entries int
+	Total   time.Duration
+}
+
+// MonthPeriod returns the first and the last instant of the month that contains t.
+func MonthPeriod(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, 0).Add(-time.Microsecond)
+}
+
+func NewTimesheet(user models.User, month time.Time, tasks []models.Task) Timesheet {
+	start, end := MonthPeriod(month)
+	timesheet := Timesheet{User: user, Month: start}
+
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		timesheet.Days = append(timesheet.Days, TimesheetDay{Date: day})
+	}
+
+	for _, task := range tasks {
+		// Task times are stored without a time zone, so the wall clock day is the one that counts.
+		index := task.StartTime.Day() - 1
+		if index < 0 || index >= len(timesheet.Days) {
+			continue
+		}
+		duration := task.EndTime.Sub(task.StartTime)
+		timesheet.Days[index].Entries++
+		timesheet.Days[index].Duration += duration
+		timesheet.Entries++
+		timesheet.Total += duration
+	}
+	return timesheet
+}
+
+// TimesheetTables builds the spreadsheet form of the timesheets: a table with a row per
+// user and day, and a summary table with a row per user.
+func TimesheetTables(timesheets ...Timesheet) (Table, Table) {
+	days := Table{
+		Name:    "Days",
+		Columns: []string{"User ID", "Surname", "Name", "Date", "Entries", "Hours", "Minutes", "Total hours"},
+	}
+	summary := Table{
+		Name: "Summary",
+		Columns: []string{"User ID", "Surname", "Name", "Patronymic", "Month",
+			"Entries", "Hours", "Minutes", "Total hours"},
+	}
+	for _, timesheet := range timesheets {
+		user := timesheet.User
+		for _, day := range timesheet.Days {
+			days.Rows = append(days.Rows, []interface{}{
+				user.ID, user.Surname, user.Name, day.Date.Format("2006-01-02"),
+				day.Entries, int(day.Duration.Hours()), int(day.Duration.Minutes()) % 60, day.Duration.Hours(),
+			})
+		}
+		summary.Rows = append(summary.Rows, []interface{}{
+			user.ID, user.Surname, user.Name, user.Patronymic, timesheet.Month.Format("2006-01"),
+			timesheet.Entries, int(timesheet.Total.Hours()), int(timesheet.Total.Minutes()) % 60, timesheet.Total.Hours(),
+		})
+	}
+	return days, summary
+}
@@ -0,0 +1,33 @@
+// Package graphqlapi answers GraphQL queries over users, their time entries and the
+// effort spent, so reports can fetch in one request what takes a request per user in
+// the REST API. Lookups are batched per request: the time entries or users wanted by
+// the elements of a list are fetched in one query, not one per element.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// maxDepth limits how deeply a query may nest, as every level can multiply the rows
+// it reads.
+const maxDepth = 10
+
+//go:embed schema.graphql
+var schemaSDL string
+
+var schema = graphql.MustParseSchema(schemaSDL, &queryResolver{}, graphql.MaxDepth(maxDepth))
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Execute runs a query with its own batching loaders. Errors are reported in the
+// response, next to whatever data could be resolved.
+func Execute(ctx context.Context, req Request) *graphql.Response {
+	return schema.Exec(withLoaders(ctx), req.Query, req.OperationName, req.Variables)
+}
@@ -0,0 +1,170 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/models"
+)
+
+// batchLoader loads values by key for one request. Keys announced with Prime are
+// fetched together with the first key that is loaded, so a field resolved on every
+// element of a list costs one query instead of one per element. Loaded values are kept
+// for the rest of the request.
+type batchLoader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu     sync.Mutex
+	primed map[K]struct{}
+	done   map[K]struct{}
+	values map[K]V
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:  fetch,
+		primed: make(map[K]struct{}),
+		done:   make(map[K]struct{}),
+		values: make(map[K]V),
+	}
+}
+
+// Prime announces keys that are about to be loaded.
+func (l *batchLoader[K, V]) Prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.done[key]; !ok {
+			l.primed[key] = struct{}{}
+		}
+	}
+}
+
+// Load returns the value of key and whether there is one, fetching it along with all
+// primed keys unless it has been fetched already.
+func (l *batchLoader[K, V]) Load(key K) (V, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.done[key]; !ok {
+		l.primed[key] = struct{}{}
+		keys := make([]K, 0, len(l.primed))
+		for k := range l.primed {
+			keys = append(keys, k)
+		}
+
+		values, err := l.fetch(keys)
+		if err != nil {
+			var zero V
+			return zero, false, err
+		}
+		for _, k := range keys {
+			delete(l.primed, k)
+			l.done[k] = struct{}{}
+		}
+		for k, v := range values {
+			l.values[k] = v
+		}
+	}
+
+	value, ok := l.values[key]
+	return value, ok, nil
+}
+
+// entriesKey selects the time entries of a user in a period. The times are in UTC, so
+// equal periods make equal keys.
+type entriesKey struct {
+	userID     int
+	start, end time.Time
+}
+
+// loaders batch the database lookups of one request.
+type loaders struct {
+	users   *batchLoader[int, models.User]
+	entries *batchLoader[entriesKey, []models.Task]
+
+	mu sync.Mutex
+	// seenUsers are the users resolved so far, whose time entries are fetched together.
+	seenUsers map[int]struct{}
+}
+
+func newLoaders() *loaders {
+	return &loaders{
+		users:     newBatchLoader(fetchUsers),
+		entries:   newBatchLoader(fetchEntries),
+		seenUsers: make(map[int]struct{}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, newLoaders())
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// seeUsers records users that have been resolved, so a time entry field on any of them
+// fetches the entries of all of them.
+func (l *loaders) seeUsers(users ...models.User) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, user := range users {
+		l.seenUsers[user.ID] = struct{}{}
+	}
+}
+
+// userEntries returns the time entries of a user in the period, in the order they
+// were started.
+func (l *loaders) userEntries(userID int, start, end time.Time) ([]models.Task, error) {
+	start, end = start.UTC(), end.UTC()
+
+	l.mu.Lock()
+	keys := make([]entriesKey, 0, len(l.seenUsers))
+	for seen := range l.seenUsers {
+		keys = append(keys, entriesKey{userID: seen, start: start, end: end})
+	}
+	l.mu.Unlock()
+
+	l.entries.Prime(keys...)
+	entries, _, err := l.entries.Load(entriesKey{userID: userID, start: start, end: end})
+	return entries, err
+}
+
+func fetchUsers(ids []int) (map[int]models.User, error) {
+	users, err := database.GetAllUsers(models.UserFilter{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+// fetchEntries makes one query per period for all the users wanted in it.
+func fetchEntries(keys []entriesKey) (map[entriesKey][]models.Task, error) {
+	type period struct{ start, end time.Time }
+	usersByPeriod := make(map[period][]int)
+	for _, key := range keys {
+		p := period{key.start, key.end}
+		usersByPeriod[p] = append(usersByPeriod[p], key.userID)
+	}
+
+	entries := make(map[entriesKey][]models.Task, len(keys))
+	for p, userIDs := range usersByPeriod {
+		tasks, err := database.GetTasksByUsers(userIDs, p.start, p.end)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			key := entriesKey{userID: task.UserID, start: p.start, end: p.end}
+			entries[key] = append(entries[key], task)
+		}
+	}
+	return entries, nil
+}
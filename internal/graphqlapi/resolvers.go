@@ -0,0 +1,422 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	graphql "github.com/graph-gophers/graphql-go"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/models"
+	"time-tracker/internal/service"
+)
+
+type queryResolver struct{}
+
+type userFilterInput struct {
+	Ids            *[]graphql.ID
+	Surname        *string
+	Name           *string
+	Patronymic     *string
+	Address        *string
+	PassportNumber *string
+	Team           *string
+	Match          *string
+	Query          *string
+	SortBy         *string
+	SortDesc       *bool
+}
+
+type usersArgs struct {
+	Filter   *userFilterInput
+	Page     int32
+	PageSize int32
+}
+
+type userPage struct {
+	items []*userResolver
+	total int32
+}
+
+func (p *userPage) Items() []*userResolver {
+	return p.items
+}
+
+func (p *userPage) Total() int32 {
+	return p.total
+}
+
+func (q *queryResolver) Users(ctx context.Context, args usersArgs) (*userPage, error) {
+	filter, err := args.Filter.userFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := args.Page, args.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	pageReq := models.OffsetPage(int(page), int(pageSize))
+
+	result, err := service.ListUsers(filter, pageReq)
+	if errors.Is(err, service.ErrInvalidFilter) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting users: %v", err)
+	}
+
+	loadersFrom(ctx).seeUsers(result.Users...)
+	return &userPage{items: userResolvers(result.Users), total: int32(result.Total)}, nil
+}
+
+// userFilter builds the database filter the way the REST API reads it from the query
+// string.
+func (f *userFilterInput) userFilter() (models.UserFilter, error) {
+	var filter models.UserFilter
+	if f == nil {
+		return filter, nil
+	}
+	for _, s := range []struct {
+		value  *string
+		target *string
+	}{
+		{f.Surname, &filter.Surname},
+		{f.Name, &filter.Name},
+		{f.Patronymic, &filter.Patronymic},
+		{f.Address, &filter.Address},
+		{f.PassportNumber, &filter.PassportNumber},
+		{f.Team, &filter.Team},
+		{f.Match, &filter.Match},
+		{f.Query, &filter.Query},
+		{f.SortBy, &filter.SortBy},
+	} {
+		if s.value != nil {
+			*s.target = *s.value
+		}
+	}
+	if f.SortDesc != nil {
+		filter.SortDesc = *f.SortDesc
+	}
+
+	if f.Ids != nil {
+		for _, id := range *f.Ids {
+			userID, err := parseID(id, "user")
+			if err != nil {
+				return filter, err
+			}
+			filter.IDs = append(filter.IDs, userID)
+		}
+	}
+	return filter, nil
+}
+
+func (q *queryResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	userID, err := parseID(args.ID, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	user, ok, err := l.users.Load(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %v", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	l.seeUsers(user)
+	return &userResolver{user: user}, nil
+}
+
+func (q *queryResolver) TimeEntry(ctx context.Context, args struct{ ID graphql.ID }) (*timeEntryResolver, error) {
+	taskID, err := parseID(args.ID, "time entry")
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := database.GetTask(taskID)
+	if errors.Is(err, database.ErrTaskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting time entry: %v", err)
+	}
+	return &timeEntryResolver{task: task}, nil
+}
+
+func parseID(id graphql.ID, kind string) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid %s id: %s", kind, id)
+	}
+	return value, nil
+}
+
+type userResolver struct {
+	user models.User
+}
+
+func userResolvers(users []models.User) []*userResolver {
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		resolvers[i] = &userResolver{user: user}
+	}
+	return resolvers
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(u.user.ID))
+}
+
+func (u *userResolver) Surname() string {
+	return u.user.Surname
+}
+
+func (u *userResolver) Name() string {
+	return u.user.Name
+}
+
+func (u *userResolver) Patronymic() string {
+	return u.user.Patronymic
+}
+
+func (u *userResolver) Address() string {
+	return u.user.Address
+}
+
+func (u *userResolver) PassportNumber() string {
+	return u.user.PassportNumber
+}
+
+func (u *userResolver) Team() string {
+	return u.user.Team
+}
+
+func (u *userResolver) Version() int32 {
+	return int32(u.user.Version)
+}
+
+// periodArgs limit a listing to the time entries started within the period, when both
+// ends are given, like the startPeriod and endPeriod query parameters.
+type periodArgs struct {
+	StartPeriod *graphql.Time
+	EndPeriod   *graphql.Time
+}
+
+func (p periodArgs) period() (time.Time, time.Time) {
+	if p.StartPeriod == nil || p.EndPeriod == nil {
+		return time.Time{}, time.Time{}
+	}
+	return p.StartPeriod.Time, p.EndPeriod.Time
+}
+
+func (u *userResolver) entries(ctx context.Context, args periodArgs) ([]models.Task, error) {
+	start, end := args.period()
+	entries, err := loadersFrom(ctx).userEntries(u.user.ID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting time entries: %v", err)
+	}
+	return entries, nil
+}
+
+func (u *userResolver) TimeEntries(ctx context.Context, args periodArgs) ([]*timeEntryResolver, error) {
+	entries, err := u.entries(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return timeEntryResolvers(ctx, entries), nil
+}
+
+func (u *userResolver) Effort(ctx context.Context, args periodArgs) (*effortResolver, error) {
+	entries, err := u.entries(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return newEffortResolver(entries), nil
+}
+
+func (u *userResolver) Projects(ctx context.Context, args periodArgs) ([]*projectResolver, error) {
+	entries, err := u.entries(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]models.Task)
+	for _, entry := range entries {
+		if name := projectName(entry.Title); name != "" {
+			byName[name] = append(byName[name], entry)
+		}
+	}
+	projects := make([]*projectResolver, 0, len(byName))
+	for name, projectEntries := range byName {
+		projects = append(projects, &projectResolver{name: name, entries: projectEntries})
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].name < projects[j].name })
+	return projects, nil
+}
+
+type timeEntryResolver struct {
+	task models.Task
+}
+
+// timeEntryResolvers also announces the users of the entries, so resolving the user of
+// each entry takes one query.
+func timeEntryResolvers(ctx context.Context, entries []models.Task) []*timeEntryResolver {
+	userIDs := make([]int, len(entries))
+	resolvers := make([]*timeEntryResolver, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+		resolvers[i] = &timeEntryResolver{task: entry}
+	}
+	loadersFrom(ctx).users.Prime(userIDs...)
+	return resolvers
+}
+
+func (e *timeEntryResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(e.task.TaskID))
+}
+
+func (e *timeEntryResolver) User(ctx context.Context) (*userResolver, error) {
+	l := loadersFrom(ctx)
+	user, ok, err := l.users.Load(e.task.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %v", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not exist", e.task.UserID)
+	}
+	l.seeUsers(user)
+	return &userResolver{user: user}, nil
+}
+
+func (e *timeEntryResolver) Task() *taskResolver {
+	return &taskResolver{task: e.task}
+}
+
+func (e *timeEntryResolver) StartTime() graphql.Time {
+	return graphql.Time{Time: e.task.StartTime}
+}
+
+func (e *timeEntryResolver) EndTime() *graphql.Time {
+	if e.task.EndTime.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: e.task.EndTime}
+}
+
+func (e *timeEntryResolver) Running() bool {
+	return e.task.EndTime.IsZero()
+}
+
+func (e *timeEntryResolver) DurationSeconds() int32 {
+	end := e.task.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return int32(end.Sub(e.task.StartTime).Seconds())
+}
+
+func (e *timeEntryResolver) Version() int32 {
+	return int32(e.task.Version)
+}
+
+type taskResolver struct {
+	task models.Task
+}
+
+func (t *taskResolver) Title() string {
+	return t.task.Title
+}
+
+func (t *taskResolver) Description() string {
+	return t.task.Description
+}
+
+// Project returns the project of the task with all of the user's time entries in it.
+func (t *taskResolver) Project(ctx context.Context) (*projectResolver, error) {
+	name := projectName(t.task.Title)
+	if name == "" {
+		return nil, nil
+	}
+
+	entries, err := loadersFrom(ctx).userEntries(t.task.UserID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting time entries: %v", err)
+	}
+	project := &projectResolver{name: name}
+	for _, entry := range entries {
+		if projectName(entry.Title) == name {
+			project.entries = append(project.entries, entry)
+		}
+	}
+	return project, nil
+}
+
+// projectName is the part of a task title before " / ", the way the tracker imports
+// join project and task names.
+func projectName(title string) string {
+	name, _, found := strings.Cut(title, " / ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+type projectResolver struct {
+	name    string
+	entries []models.Task
+}
+
+func (p *projectResolver) Name() string {
+	return p.name
+}
+
+func (p *projectResolver) TimeEntries(ctx context.Context) []*timeEntryResolver {
+	return timeEntryResolvers(ctx, p.entries)
+}
+
+func (p *projectResolver) Effort() *effortResolver {
+	return newEffortResolver(p.entries)
+}
+
+type effortResolver struct {
+	count int
+	total time.Duration
+}
+
+// newEffortResolver sums up the finished time entries; running timers do not count
+// yet, as in the worklog.
+func newEffortResolver(entries []models.Task) *effortResolver {
+	effort := &effortResolver{}
+	for _, entry := range entries {
+		if entry.EndTime.IsZero() {
+			continue
+		}
+		effort.count++
+		effort.total += entry.EndTime.Sub(entry.StartTime)
+	}
+	return effort
+}
+
+func (e *effortResolver) TimeEntries() int32 {
+	return int32(e.count)
+}
+
+func (e *effortResolver) TotalSeconds() int32 {
+	return int32(e.total.Seconds())
+}
+
+func (e *effortResolver) Hours() int32 {
+	return int32(e.total.Hours())
+}
+
+func (e *effortResolver) Minutes() int32 {
+	return int32(e.total.Minutes()) % 60
+}
@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/ratelimit"
+	"time-tracker/internal/service"
+	"time-tracker/internal/validation"
+)
+
+// toStatus converts an error of the service or database layer to the status the REST
+// API would answer with. Unexpected errors are logged with the action that failed.
+func toStatus(err error, action string) error {
+	var fieldErrs validation.Errors
+	var limitErr *ratelimit.ExceededError
+	switch {
+	case errors.As(err, &fieldErrs):
+		return invalidFields(fieldErrs)
+	case errors.Is(err, database.ErrUserNotFound), errors.Is(err, database.ErrTaskNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrUserExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, database.ErrEmptyUpdate), errors.Is(err, service.ErrInvalidFilter),
+		errors.Is(err, database.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrVersionRequired):
+		return status.Error(codes.FailedPrecondition, "the version is required, read the user and pass its version")
+	case errors.Is(err, database.ErrVersionMismatch):
+		return status.Error(codes.Aborted, "the version has changed, reload and retry")
+	case errors.Is(err, database.ErrTimerStopped), errors.Is(err, database.ErrUserAnonymized):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &limitErr):
+		st, detailsErr := status.New(codes.ResourceExhausted, err.Error()).
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(limitErr.RetryAfter)})
+		if detailsErr != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return st.Err()
+	}
+	logger.Logger.Error("Error "+action, zap.Error(err))
+	return status.Errorf(codes.Internal, "error %s: %v", action, err)
+}
+
+// invalidFields reports validation errors as INVALID_ARGUMENT with a BadRequest detail
+// listing each field, the counterpart of the JSON body of a REST 400.
+func invalidFields(fieldErrs validation.Errors) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, fieldErr := range fieldErrs {
+		badRequest.FieldViolations = append(badRequest.FieldViolations,
+			&errdetails.BadRequest_FieldViolation{Field: fieldErr.Field, Description: fieldErr.Message})
+	}
+	st, err := status.New(codes.InvalidArgument, "validation failed: "+fieldErrs.Error()).WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, fieldErrs.Error())
+	}
+	return st.Err()
+}
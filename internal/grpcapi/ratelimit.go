@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time-tracker/internal/config"
+	"time-tracker/internal/grpcapi/timetrackerv1"
+	"time-tracker/internal/ratelimit"
+)
+
+// rateLimits are the limits of the REST API, applied to the calls of each peer.
+type rateLimits struct {
+	client     *ratelimit.Limiter
+	write      *ratelimit.Limiter
+	enrichment *ratelimit.Limiter
+}
+
+// limits are unlimited until SetRateLimits is called.
+var limits = newRateLimits(&config.Config{})
+
+func newRateLimits(cfg *config.Config) *rateLimits {
+	return &rateLimits{
+		client:     ratelimit.New(cfg.RateLimit),
+		write:      ratelimit.New(cfg.WriteRateLimit),
+		enrichment: ratelimit.New(cfg.EnrichmentRateLimit),
+	}
+}
+
+// SetRateLimits limits the calls of each peer like the REST API limits the requests of
+// each client.
+func SetRateLimits(cfg *config.Config) {
+	limits = newRateLimits(cfg)
+}
+
+// writeMethods are the methods that change data, limited per method like the POST,
+// PUT, PATCH and DELETE requests to a route.
+var writeMethods = map[string]bool{
+	timetrackerv1.TimeTracker_CreateUser_FullMethodName: true,
+	timetrackerv1.TimeTracker_UpdateUser_FullMethodName: true,
+	timetrackerv1.TimeTracker_DeleteUser_FullMethodName: true,
+	timetrackerv1.TimeTracker_StartTimer_FullMethodName: true,
+	timetrackerv1.TimeTracker_StopTimer_FullMethodName:  true,
+}
+
+// limitCalls rejects a call with RESOURCE_EXHAUSTED once its peer reached a limit.
+// Peers are told apart by IP address, as the metadata is chosen by the caller.
+func limitCalls(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	client := "ip:" + peerHost(ctx)
+	if err := limits.client.Allow(client); err != nil {
+		return nil, toStatus(err, "limiting calls")
+	}
+	if writeMethods[info.FullMethod] {
+		if err := limits.write.Allow(client + " " + info.FullMethod); err != nil {
+			return nil, toStatus(err, "limiting calls")
+		}
+	}
+	if info.FullMethod == timetrackerv1.TimeTracker_CreateUser_FullMethodName {
+		if err := limits.enrichment.Allow(client); err != nil {
+			return nil, toStatus(err, "limiting calls")
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,48 @@
+package grpcapi
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"time-tracker/internal/models"
+)
+
+const defaultPageSize = 10
+
+// actor identifies the caller for the audit log like the REST API does: by the peer's
+// IP address, with the unverified x-actor metadata recorded as claimed.
+func actor(ctx context.Context) models.Actor {
+	var claimed string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-actor"); len(values) > 0 {
+			claimed = values[0]
+		}
+	}
+	return models.NewActor(peerHost(ctx), claimed)
+}
+
+// peerHost returns the IP address of the caller.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// pageRequest selects a page by number, defaulting like the page and pageSize query
+// parameters of the REST API.
+func pageRequest(page, pageSize int32) models.PageRequest {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return models.OffsetPage(int(page), int(pageSize))
+}
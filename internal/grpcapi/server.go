@@ -0,0 +1,71 @@
+// Package grpcapi serves the TimeTracker gRPC service for internal services, next to
+// the REST API and on top of the same service and database layers. The server also
+// offers the standard health service and server reflection, so grpcurl and load
+// balancers work without the proto file.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=time-tracker --go-grpc_out=../.. --go-grpc_opt=module=time-tracker timetracker/v1/timetracker.proto
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"net"
+	"time"
+	"time-tracker/internal/grpcapi/timetrackerv1"
+	"time-tracker/internal/logger"
+)
+
+type server struct {
+	timetrackerv1.UnimplementedTimeTrackerServer
+}
+
+// NewServer returns a gRPC server with the TimeTracker, health and reflection services
+// registered.
+func NewServer() (*grpc.Server, *health.Server) {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(logCalls, limitCalls))
+	timetrackerv1.RegisterTimeTrackerServer(s, &server{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(timetrackerv1.TimeTracker_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+	return s, healthServer
+}
+
+// Serve listens on addr and serves gRPC until the context is cancelled. The listener is
+// opened before Serve returns, so a taken address is reported to the caller.
+func Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s, healthServer := NewServer()
+	go func() {
+		<-ctx.Done()
+		healthServer.Shutdown()
+		s.GracefulStop()
+	}()
+	go func() {
+		logger.Logger.Info("gRPC server listening", zap.String("addr", addr))
+		if err := s.Serve(listener); err != nil {
+			logger.Logger.Error("gRPC server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// logCalls logs each call like the request logger of the REST API.
+func logCalls(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logger.Logger.Info("gRPC call", zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()), zap.Duration("duration", time.Since(start)))
+	return resp, err
+}
@@ -0,0 +1,105 @@
+package grpcapi
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/grpcapi/timetrackerv1"
+	"time-tracker/internal/models"
+	"time-tracker/internal/service"
+)
+
+func (s *server) StartTimer(ctx context.Context, req *timetrackerv1.StartTimerRequest) (*timetrackerv1.TimeEntry, error) {
+	if req.UserId < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %d", req.UserId)
+	}
+	if _, err := database.GetUser(int(req.UserId)); err != nil {
+		return nil, toStatus(err, "getting user")
+	}
+
+	taskReq := models.TaskRequest{Title: req.Title, Description: req.Description}
+	taskID, err := service.StartTimer(actor(ctx), int(req.UserId), taskReq)
+	if err != nil {
+		return nil, toStatus(err, "starting task")
+	}
+
+	task, err := database.GetTask(taskID)
+	if err != nil {
+		return nil, toStatus(err, "getting task")
+	}
+	return taskToProto(task), nil
+}
+
+func (s *server) StopTimer(ctx context.Context, req *timetrackerv1.StopTimerRequest) (*timetrackerv1.TimeEntry, error) {
+	if req.TimeEntryId < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid time entry id: %d", req.TimeEntryId)
+	}
+	taskID := int(req.TimeEntryId)
+
+	if err := service.StopTimer(actor(ctx), taskID, int(req.Version)); err != nil {
+		return nil, toStatus(err, "stopping task")
+	}
+
+	task, err := database.GetTask(taskID)
+	if err != nil {
+		return nil, toStatus(err, "getting task")
+	}
+	return taskToProto(task), nil
+}
+
+func (s *server) GetEffort(ctx context.Context, req *timetrackerv1.GetEffortRequest) (*timetrackerv1.GetEffortResponse, error) {
+	if req.UserId < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %d", req.UserId)
+	}
+	if _, err := database.GetUser(int(req.UserId)); err != nil {
+		return nil, toStatus(err, "getting user")
+	}
+
+	filter := models.TaskFilter{UserID: int(req.UserId)}
+	if req.StartPeriod != nil && req.EndPeriod != nil {
+		filter.Start, filter.End = req.StartPeriod.AsTime(), req.EndPeriod.AsTime()
+	}
+
+	tasks, _, err := database.GetTasksPage(filter, pageRequest(req.Page, req.PageSize))
+	if err != nil {
+		return nil, toStatus(err, "getting tasks")
+	}
+	total, err := database.CountTasks(filter)
+	if err != nil {
+		return nil, toStatus(err, "counting tasks")
+	}
+
+	resp := &timetrackerv1.GetEffortResponse{Total: int32(total)}
+	for _, effort := range models.CalculateUserEffort(tasks) {
+		resp.Efforts = append(resp.Efforts, &timetrackerv1.Effort{
+			TimeEntryId: int64(effort.TaskID),
+			Hours:       int32(effort.Hours),
+			Minutes:     int32(effort.Minutes),
+		})
+	}
+	return resp, nil
+}
+
+func taskToProto(task models.Task) *timetrackerv1.TimeEntry {
+	return &timetrackerv1.TimeEntry{
+		Id:          int64(task.TaskID),
+		UserId:      int64(task.UserID),
+		Title:       task.Title,
+		Description: task.Description,
+		StartTime:   timestampToProto(task.StartTime),
+		EndTime:     timestampToProto(task.EndTime),
+		Version:     int64(task.Version),
+	}
+}
+
+// timestampToProto leaves a zero time, the end of a running timer, unset. Task times
+// are stored without a zone and read on the server's wall clock.
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(models.WallClock(t))
+}
@@ -0,0 +1,412 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: timetracker/v1/timetracker.proto
+
+package timetrackerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TimeTracker_ListUsers_FullMethodName  = "/timetracker.v1.TimeTracker/ListUsers"
+	TimeTracker_GetUser_FullMethodName    = "/timetracker.v1.TimeTracker/GetUser"
+	TimeTracker_CreateUser_FullMethodName = "/timetracker.v1.TimeTracker/CreateUser"
+	TimeTracker_UpdateUser_FullMethodName = "/timetracker.v1.TimeTracker/UpdateUser"
+	TimeTracker_DeleteUser_FullMethodName = "/timetracker.v1.TimeTracker/DeleteUser"
+	TimeTracker_StartTimer_FullMethodName = "/timetracker.v1.TimeTracker/StartTimer"
+	TimeTracker_StopTimer_FullMethodName  = "/timetracker.v1.TimeTracker/StopTimer"
+	TimeTracker_GetEffort_FullMethodName  = "/timetracker.v1.TimeTracker/GetEffort"
+)
+
+// TimeTrackerClient is the client API for TimeTracker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TimeTracker is the gRPC counterpart of the /api/v1 REST API for internal services.
+// Errors carry the gRPC status code matching the REST status: NOT_FOUND, ALREADY_EXISTS,
+// INVALID_ARGUMENT with google.rpc.BadRequest details for invalid fields, ABORTED when
+// the expected version no longer matches, FAILED_PRECONDITION for a stopped timer and
+// RESOURCE_EXHAUSTED when the people info service quota is used up.
+type TimeTrackerClient interface {
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error)
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	// CreateUser adds the holder of a passport, filling in the rest from the people
+	// info service.
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error)
+	// UpdateUser changes the fields that are set in the request.
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error)
+	// DeleteUser deletes a user with all time entries.
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	StartTimer(ctx context.Context, in *StartTimerRequest, opts ...grpc.CallOption) (*TimeEntry, error)
+	StopTimer(ctx context.Context, in *StopTimerRequest, opts ...grpc.CallOption) (*TimeEntry, error)
+	// GetEffort reports the time a user spent on each finished time entry, longest
+	// first, like the worklog page.
+	GetEffort(ctx context.Context, in *GetEffortRequest, opts ...grpc.CallOption) (*GetEffortResponse, error)
+}
+
+type timeTrackerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTimeTrackerClient(cc grpc.ClientConnInterface) TimeTrackerClient {
+	return &timeTrackerClient{cc}
+}
+
+func (c *timeTrackerClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, TimeTracker_ListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, TimeTracker_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, TimeTracker_CreateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, TimeTracker_UpdateUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, TimeTracker_DeleteUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) StartTimer(ctx context.Context, in *StartTimerRequest, opts ...grpc.CallOption) (*TimeEntry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TimeEntry)
+	err := c.cc.Invoke(ctx, TimeTracker_StartTimer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) StopTimer(ctx context.Context, in *StopTimerRequest, opts ...grpc.CallOption) (*TimeEntry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TimeEntry)
+	err := c.cc.Invoke(ctx, TimeTracker_StopTimer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *timeTrackerClient) GetEffort(ctx context.Context, in *GetEffortRequest, opts ...grpc.CallOption) (*GetEffortResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetEffortResponse)
+	err := c.cc.Invoke(ctx, TimeTracker_GetEffort_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TimeTrackerServer is the server API for TimeTracker service.
+// All implementations must embed UnimplementedTimeTrackerServer
+// for forward compatibility.
+//
+// TimeTracker is the gRPC counterpart of the /api/v1 REST API for internal services.
+// Errors carry the gRPC status code matching the REST status: NOT_FOUND, ALREADY_EXISTS,
+// INVALID_ARGUMENT with google.rpc.BadRequest details for invalid fields, ABORTED when
+// the expected version no longer matches, FAILED_PRECONDITION for a stopped timer and
+// RESOURCE_EXHAUSTED when the people info service quota is used up.
+type TimeTrackerServer interface {
+	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	// CreateUser adds the holder of a passport, filling in the rest from the people
+	// info service.
+	CreateUser(context.Context, *CreateUserRequest) (*User, error)
+	// UpdateUser changes the fields that are set in the request.
+	UpdateUser(context.Context, *UpdateUserRequest) (*User, error)
+	// DeleteUser deletes a user with all time entries.
+	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
+	StartTimer(context.Context, *StartTimerRequest) (*TimeEntry, error)
+	StopTimer(context.Context, *StopTimerRequest) (*TimeEntry, error)
+	// GetEffort reports the time a user spent on each finished time entry, longest
+	// first, like the worklog page.
+	GetEffort(context.Context, *GetEffortRequest) (*GetEffortResponse, error)
+	mustEmbedUnimplementedTimeTrackerServer()
+}
+
+// UnimplementedTimeTrackerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTimeTrackerServer struct{}
+
+func (UnimplementedTimeTrackerServer) ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedTimeTrackerServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedTimeTrackerServer) CreateUser(context.Context, *CreateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedTimeTrackerServer) UpdateUser(context.Context, *UpdateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedTimeTrackerServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedTimeTrackerServer) StartTimer(context.Context, *StartTimerRequest) (*TimeEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartTimer not implemented")
+}
+func (UnimplementedTimeTrackerServer) StopTimer(context.Context, *StopTimerRequest) (*TimeEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopTimer not implemented")
+}
+func (UnimplementedTimeTrackerServer) GetEffort(context.Context, *GetEffortRequest) (*GetEffortResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEffort not implemented")
+}
+func (UnimplementedTimeTrackerServer) mustEmbedUnimplementedTimeTrackerServer() {}
+func (UnimplementedTimeTrackerServer) testEmbeddedByValue()                     {}
+
+// UnsafeTimeTrackerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TimeTrackerServer will
+// result in compilation errors.
+type UnsafeTimeTrackerServer interface {
+	mustEmbedUnimplementedTimeTrackerServer()
+}
+
+func RegisterTimeTrackerServer(s grpc.ServiceRegistrar, srv TimeTrackerServer) {
+	// If the following call pancis, it indicates UnimplementedTimeTrackerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TimeTracker_ServiceDesc, srv)
+}
+
+func _TimeTracker_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_UpdateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_StartTimer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartTimerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).StartTimer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_StartTimer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).StartTimer(ctx, req.(*StartTimerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_StopTimer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopTimerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).StopTimer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_StopTimer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).StopTimer(ctx, req.(*StopTimerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TimeTracker_GetEffort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEffortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TimeTrackerServer).GetEffort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TimeTracker_GetEffort_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TimeTrackerServer).GetEffort(ctx, req.(*GetEffortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TimeTracker_ServiceDesc is the grpc.ServiceDesc for TimeTracker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TimeTracker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "timetracker.v1.TimeTracker",
+	HandlerType: (*TimeTrackerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUsers",
+			Handler:    _TimeTracker_ListUsers_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _TimeTracker_GetUser_Handler,
+		},
+		{
+			MethodName: "CreateUser",
+			Handler:    _TimeTracker_CreateUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _TimeTracker_UpdateUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _TimeTracker_DeleteUser_Handler,
+		},
+		{
+			MethodName: "StartTimer",
+			Handler:    _TimeTracker_StartTimer_Handler,
+		},
+		{
+			MethodName: "StopTimer",
+			Handler:    _TimeTracker_StopTimer_Handler,
+		},
+		{
+			MethodName: "GetEffort",
+			Handler:    _TimeTracker_GetEffort_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "timetracker/v1/timetracker.proto",
+}
@@ -0,0 +1,108 @@
+package grpcapi
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"time-tracker/internal/database"
+	"time-tracker/internal/grpcapi/timetrackerv1"
+	"time-tracker/internal/models"
+	"time-tracker/internal/service"
+)
+
+func (s *server) ListUsers(ctx context.Context, req *timetrackerv1.ListUsersRequest) (*timetrackerv1.ListUsersResponse, error) {
+	filter := models.UserFilter{
+		Surname:        req.Surname,
+		Name:           req.Name,
+		Patronymic:     req.Patronymic,
+		Address:        req.Address,
+		PassportNumber: req.PassportNumber,
+		Team:           req.Team,
+		Match:          req.Match,
+		Query:          req.Query,
+		SortBy:         req.SortBy,
+		SortDesc:       req.SortDesc,
+	}
+	page, err := service.ListUsers(filter, pageRequest(req.Page, req.PageSize))
+	if err != nil {
+		return nil, toStatus(err, "getting users")
+	}
+
+	resp := &timetrackerv1.ListUsersResponse{Total: int32(page.Total)}
+	for _, user := range page.Users {
+		resp.Users = append(resp.Users, userToProto(user))
+	}
+	return resp, nil
+}
+
+func (s *server) GetUser(ctx context.Context, req *timetrackerv1.GetUserRequest) (*timetrackerv1.User, error) {
+	if req.Id < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %d", req.Id)
+	}
+	user, err := database.GetUser(int(req.Id))
+	if err != nil {
+		return nil, toStatus(err, "getting user")
+	}
+	return userToProto(user), nil
+}
+
+func (s *server) CreateUser(ctx context.Context, req *timetrackerv1.CreateUserRequest) (*timetrackerv1.User, error) {
+	user, err := service.CreateUser(actor(ctx), "passport_number", req.PassportNumber)
+	if err != nil {
+		return nil, toStatus(err, "adding user")
+	}
+	return userToProto(user), nil
+}
+
+func (s *server) UpdateUser(ctx context.Context, req *timetrackerv1.UpdateUserRequest) (*timetrackerv1.User, error) {
+	if req.Id < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %d", req.Id)
+	}
+
+	update := models.UserUpdate{
+		Surname:        optionalString(req.Surname),
+		Name:           optionalString(req.Name),
+		Patronymic:     optionalString(req.Patronymic),
+		Address:        optionalString(req.Address),
+		PassportNumber: optionalString(req.PassportNumber),
+		Team:           optionalString(req.Team),
+	}
+	user, err := service.UpdateUser(actor(ctx), int(req.Id), int(req.Version), update, "passport_number")
+	if err != nil {
+		return nil, toStatus(err, "updating user")
+	}
+	return userToProto(user), nil
+}
+
+func (s *server) DeleteUser(ctx context.Context, req *timetrackerv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	if req.Id < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %d", req.Id)
+	}
+	if err := service.DeleteUser(actor(ctx), int(req.Id), int(req.Version)); err != nil {
+		return nil, toStatus(err, "deleting user")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// optionalString maps a proto3 optional field to a merge patch field: unset keeps the
+// value and an empty string clears it.
+func optionalString(value *string) models.OptionalString {
+	if value == nil {
+		return models.OptionalString{}
+	}
+	return models.OptionalString{Set: true, Value: *value}
+}
+
+func userToProto(user models.User) *timetrackerv1.User {
+	return &timetrackerv1.User{
+		Id:             int64(user.ID),
+		Surname:        user.Surname,
+		Name:           user.Name,
+		Patronymic:     user.Patronymic,
+		Address:        user.Address,
+		PassportNumber: user.PassportNumber,
+		Team:           user.Team,
+		Version:        int64(user.Version),
+	}
+}
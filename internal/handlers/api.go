@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/service"
+)
+
+// APIPrefix is where the versioned API is mounted. The handlers below serve its
+// resource routes, which answer in JSON; the routes they replace are kept outside the
+// prefix for existing clients.
+const APIPrefix = "/api/v1"
+
+type userListPage struct {
+	Items []models.User   `json:"items"`
+	Page  models.PageInfo `json:"page"`
+}
+
+type timeEntryPage struct {
+	Items []models.Task   `json:"items"`
+	Page  models.PageInfo `json:"page"`
+}
+
+// ListUsers returns a page of users. It takes the same filters as GetUsers.
+func ListUsers(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ListUsers handler called")
+	defer logger.Logger.Info("ListUsers handler finished")
+
+	users, info, ok := listUsers(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, userListPage{Items: users, Page: info})
+}
+
+// CreateUser adds a user by passport number, like AddUser, and returns the new user.
+func CreateUser(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("CreateUser handler called")
+	defer logger.Logger.Info("CreateUser handler finished")
+
+	user, ok := addUser(w, r)
+	if !ok {
+		return
+	}
+	setETag(w, user.Version)
+	w.Header().Set("Location", fmt.Sprintf("%s/users/%d", APIPrefix, user.ID))
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func GetUser(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetUser handler called")
+	defer logger.Logger.Info("GetUser handler finished")
+
+	userId, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := findUser(w, userId)
+	if !ok {
+		return
+	}
+	setETag(w, user.Version)
+	writeJSON(w, http.StatusOK, user)
+}
+
+// PatchUser applies a JSON Merge Patch (RFC 7396) to the user and returns the updated
+// user: absent fields keep their values and null clears the patronymic or the team. It
+// serves PUT as well.
+func PatchUser(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("PatchUser handler called")
+	defer logger.Logger.Info("PatchUser handler finished")
+
+	userId, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var update models.UserUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := updateUser(w, r, userId, update, "passport_number", true)
+	if !ok {
+		return
+	}
+	setETag(w, user.Version)
+	writeJSON(w, http.StatusOK, user)
+	logger.Logger.Info("User updated successfully", zap.Int("userId", userId))
+}
+
+// RemoveUser deletes a user with all time entries.
+func RemoveUser(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("RemoveUser handler called")
+	defer logger.Logger.Info("RemoveUser handler finished")
+
+	userId, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r, true, userVersion(userId))
+	if !ok {
+		return
+	}
+
+	err := service.DeleteUser(actorFromRequest(r), userId, version)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("User has been modified", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d has been modified", userId), http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error deleting user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error deleting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Logger.Info("User deleted successfully", zap.Int("userId", userId))
+}
+
+// ListTimeEntries returns a page of the user's time entries, longest first, optionally
+// limited to a period.
+func ListTimeEntries(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ListTimeEntries handler called")
+	defer logger.Logger.Info("ListTimeEntries handler finished")
+
+	filter, ok := parseTaskFilter(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := findUser(w, filter.UserID); !ok {
+		return
+	}
+
+	tasks, info, ok := listTasks(w, r, filter)
+	if !ok {
+		return
+	}
+	for i := range tasks {
+		tasks[i].StartTime, tasks[i].EndTime = models.WallClock(tasks[i].StartTime), models.WallClock(tasks[i].EndTime)
+	}
+	writeJSON(w, http.StatusOK, timeEntryPage{Items: tasks, Page: info})
+}
+
+// StartTimeEntry starts a timer for the user and returns the running time entry.
+func StartTimeEntry(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("StartTimeEntry handler called")
+	defer logger.Logger.Info("StartTimeEntry handler finished")
+
+	userId, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var taskReq models.TaskRequest
+	err := json.NewDecoder(r.Body).Decode(&taskReq)
+	if err != nil && !errors.Is(err, io.EOF) {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := findUser(w, userId); !ok {
+		return
+	}
+
+	taskID, err := service.StartTimer(actorFromRequest(r), userId, taskReq)
+	if err != nil {
+		logger.Logger.Error("Error starting task", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error starting task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	task, ok := findTask(w, taskID)
+	if !ok {
+		return
+	}
+	setETag(w, task.Version)
+	w.Header().Set("Location", fmt.Sprintf("%s/time-entries/%d", APIPrefix, taskID))
+	writeJSON(w, http.StatusCreated, task)
+	logger.Logger.Info("Task-Timer started successfully", zap.Int("userID", userId), zap.Int("taskID", taskID))
+}
+
+func GetTimeEntry(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetTimeEntry handler called")
+	defer logger.Logger.Info("GetTimeEntry handler finished")
+
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	task, ok := findTask(w, taskID)
+	if !ok {
+		return
+	}
+	setETag(w, task.Version)
+	writeJSON(w, http.StatusOK, task)
+}
+
+// StopTimeEntry stops a running timer and returns the finished time entry.
+func StopTimeEntry(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("StopTimeEntry handler called")
+	defer logger.Logger.Info("StopTimeEntry handler finished")
+
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r, true, taskVersion(taskID))
+	if !ok {
+		return
+	}
+
+	task, ok := findTask(w, taskID)
+	if !ok {
+		return
+	}
+	if !task.EndTime.IsZero() {
+		logger.Logger.Warn("Task is already stopped", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d is already stopped", taskID), http.StatusConflict)
+		return
+	}
+
+	err := service.StopTimer(actorFromRequest(r), taskID, version)
+	if errors.Is(err, database.ErrTimerStopped) {
+		logger.Logger.Warn("Task is already stopped", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d is already stopped", taskID), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("Task has been modified", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d has been modified", taskID), http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error stopping task timer", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error stopping task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	task, ok = findTask(w, taskID)
+	if !ok {
+		return
+	}
+	setETag(w, task.Version)
+	writeJSON(w, http.StatusOK, task)
+	logger.Logger.Info("Task-Timer stopped successfully", zap.Int("taskID", taskID))
+}
+
+func userIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return 0, false
+	}
+	return userId, true
+}
+
+func taskIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	taskIdString := chi.URLParam(r, "id")
+	taskID, err := strconv.Atoi(taskIdString)
+	if err != nil || taskID < 1 {
+		logger.Logger.Warn("Invalid task ID", zap.String("taskIdString", taskIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid task ID: %v", taskIdString), http.StatusBadRequest)
+		return 0, false
+	}
+	return taskID, true
+}
+
+// findUser loads a user, answering 404 when there is none.
+func findUser(w http.ResponseWriter, userId int) (models.User, bool) {
+	user, err := database.GetUser(userId)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return user, false
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting the user from the database", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting the user from the database: %v", err), http.StatusInternalServerError)
+		return user, false
+	}
+	return user, true
+}
+
+// findTask loads a time entry, answering 404 when there is none.
+func findTask(w http.ResponseWriter, taskID int) (models.Task, bool) {
+	task, err := database.GetTask(taskID)
+	if errors.Is(err, database.ErrTaskNotFound) {
+		logger.Logger.Warn("Task does not exist", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d not exist", taskID), http.StatusNotFound)
+		return task, false
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting task from database", zap.Int("taskID", taskID), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting task: %v", err), http.StatusInternalServerError)
+		return task, false
+	}
+	return task, true
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetAuditLog handler called")
+	defer logger.Logger.Info("GetAuditLog handler finished")
+
+	query := r.URL.Query()
+
+	filter := models.AuditFilter{
+		Actor:        query.Get("actor"),
+		ClaimedActor: query.Get("claimedActor"),
+		Action:       query.Get("action"),
+		TargetType:   query.Get("targetType"),
+	}
+
+	if targetIdString := query.Get("targetId"); targetIdString != "" {
+		targetId, err := strconv.Atoi(targetIdString)
+		if err != nil || targetId < 1 {
+			logger.Logger.Warn("Invalid target ID", zap.String("targetIdString", targetIdString), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Invalid target ID: %v", targetIdString), http.StatusBadRequest)
+			return
+		}
+		filter.TargetID = targetId
+	}
+
+	if fromString := query.Get("from"); fromString != "" {
+		from, err := time.Parse(time.RFC3339, fromString)
+		if err != nil {
+			logger.Logger.Warn("Invalid from format", zap.String("fromString", fromString), zap.Error(err))
+			http.Error(w, "Invalid from format", http.StatusBadRequest)
+			return
+		}
+		filter.From = from
+	}
+
+	if toString := query.Get("to"); toString != "" {
+		to, err := time.Parse(time.RFC3339, toString)
+		if err != nil {
+			logger.Logger.Warn("Invalid to format", zap.String("toString", toString), zap.Error(err))
+			http.Error(w, "Invalid to format", http.StatusBadRequest)
+			return
+		}
+		filter.To = to
+	}
+
+	page, err := parsePageRequest(query)
+	if err != nil {
+		logger.Logger.Warn("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, more, err := database.GetAuditEntries(filter, page)
+	if err != nil {
+		logger.Logger.Error("Error getting audit entries from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting audit entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := database.CountAuditEntries(filter)
+	if err != nil {
+		logger.Logger.Error("Error counting audit entries in database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting audit entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	auditCursor := func(entry models.AuditEntry) models.Cursor {
+		return models.Cursor{ID: entry.ID}
+	}
+	info := pageInfo(w, r, page, total, len(entries), more,
+		func() models.Cursor { return auditCursor(entries[0]) },
+		func() models.Cursor { return auditCursor(entries[len(entries)-1]) })
+
+	writeJSON(w, http.StatusOK, auditLogPage{Items: entries, Page: info})
+}
+
+type auditLogPage struct {
+	Items []models.AuditEntry `json:"items"`
+	Page  models.PageInfo     `json:"page"`
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/export"
+	"time-tracker/internal/importer"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// calendarDefaultPeriod is how far back the feed reaches when no period is given.
+const calendarDefaultPeriod = 90 * 24 * time.Hour
+
+type calendarTokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// CreateCalendarToken issues (or rotates) the token protecting the user's calendar feed.
+func CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("CreateCalendarToken handler called")
+	defer logger.Logger.Info("CreateCalendarToken handler finished")
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	token, err := database.CreateCalendarToken(actorFromRequest(r), userId)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error creating calendar token", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error creating calendar token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// The feed lives next to the token route (/users/{id}/calendar.ics), under the same
+	// API prefix the token was requested with.
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	feedURL := url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     strings.TrimSuffix(r.URL.Path, "/token") + ".ics",
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	writeJSON(w, http.StatusCreated, calendarTokenResponse{Token: token, URL: feedURL.String()})
+	logger.Logger.Info("Calendar token created", zap.Int("userId", userId))
+}
+
+func RevokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("RevokeCalendarToken handler called")
+	defer logger.Logger.Info("RevokeCalendarToken handler finished")
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	err = database.RevokeCalendarToken(actorFromRequest(r), userId)
+	if errors.Is(err, database.ErrCalendarTokenNotFound) {
+		logger.Logger.Warn("Calendar token does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d has no calendar token", userId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error revoking calendar token", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error revoking calendar token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Calendar token revoked"))
+	logger.Logger.Info("Calendar token revoked", zap.Int("userId", userId))
+}
+
+// GetCalendarFeed serves the user's time entries as an iCalendar feed. Calendar apps
+// cannot send headers, so the token is passed in the query string.
+func GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetCalendarFeed handler called")
+	defer logger.Logger.Info("GetCalendarFeed handler finished")
+
+	query := r.URL.Query()
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	valid, err := database.CheckCalendarToken(userId, query.Get("token"))
+	if err != nil {
+		logger.Logger.Error("Error checking calendar token", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error checking calendar token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		logger.Logger.Warn("Invalid calendar token", zap.Int("userId", userId))
+		http.Error(w, "Invalid calendar token", http.StatusUnauthorized)
+		return
+	}
+
+	// Same period parameters as the work log; without them the feed covers recent entries.
+	now := time.Now()
+	startPeriod, endPeriod := now.Add(-calendarDefaultPeriod), now
+	startPeriodString := query.Get("startPeriod")
+	endPeriodString := query.Get("endPeriod")
+	if startPeriodString != "" && endPeriodString != "" {
+		startPeriod, err = time.Parse(time.RFC3339, startPeriodString)
+		if err != nil {
+			logger.Logger.Warn("Invalid start period format", zap.String("startPeriodString", startPeriodString), zap.Error(err))
+			http.Error(w, "Invalid start period format", http.StatusBadRequest)
+			return
+		}
+
+		endPeriod, err = time.Parse(time.RFC3339, endPeriodString)
+		if err != nil {
+			logger.Logger.Warn("Invalid end period format", zap.String("endPeriodString", endPeriodString), zap.Error(err))
+			http.Error(w, "Invalid end period format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	includeRunning, err := parseBoolParam(query, "running")
+	if err != nil {
+		logger.Logger.Warn("Invalid running flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := database.GetUser(userId)
+	if err != nil {
+		logger.Logger.Error("Error getting user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tasks, err := database.GetTasksByPeriod(userId, startPeriod, endPeriod)
+	if err != nil {
+		logger.Logger.Error("Error getting tasks", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if includeRunning {
+		running, err := database.GetRunningTasksByPeriod(userId, startPeriod, endPeriod)
+		if err != nil {
+			logger.Logger.Error("Error getting running tasks", zap.Int("userId", userId), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error getting running tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+		tasks = append(tasks, running...)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].StartTime.Before(tasks[j].StartTime) })
+
+	calendar := export.Calendar{
+		Name:  "Time entries: " + strings.TrimSpace(user.Surname+" "+user.Name),
+		Tasks: tasks,
+		Now:   now,
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", contentDisposition("inline", fmt.Sprintf("user-%d.ics", userId)))
+	if err := export.WriteCalendar(w, calendar); err != nil {
+		logger.Logger.Error("Error writing calendar feed", zap.Int("userId", userId), zap.Error(err))
+		return
+	}
+	logger.Logger.Info("Calendar feed sent", zap.Int("userId", userId), zap.Int("events", len(tasks)))
+}
+
+const (
+	onConflictFail   = "fail"
+	onConflictSkip   = "skip"
+	onConflictImport = "import"
+)
+
+// ImportCalendar imports the events of an uploaded .ics file as time entries of a user.
+// The file is sent as the "file" field of a multipart form, with optional summary rules
+// as JSON in the "rules" field, or as a plain text/calendar body.
+func ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ImportCalendar handler called")
+	defer logger.Logger.Info("ImportCalendar handler finished")
+
+	query := r.URL.Query()
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	dryRun, err := parseBoolParam(query, "dryRun")
+	if err != nil {
+		logger.Logger.Warn("Invalid dry run flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options := importer.CalendarImportOptions{Now: time.Now()}
+	options.SkipUnmatched, err = parseBoolParam(query, "skipUnmatched")
+	if err != nil {
+		logger.Logger.Warn("Invalid skip unmatched flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	onConflict := query.Get("onConflict")
+	switch onConflict {
+	case "":
+		onConflict = onConflictFail
+	case onConflictFail, onConflictSkip, onConflictImport:
+	default:
+		logger.Logger.Warn("Invalid conflict mode", zap.String("onConflict", onConflict))
+		http.Error(w, fmt.Sprintf("Invalid onConflict: %s, expected fail, skip or import", onConflict), http.StatusBadRequest)
+		return
+	}
+
+	exist, err := database.CheckUserExist(userId)
+	if err != nil {
+		logger.Logger.Error("Error getting the user from the database", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting the user from the database: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !exist {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+
+	events, rules, err := readCalendarUpload(w, r)
+	if err != nil {
+		logger.Logger.Warn("Invalid calendar upload", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var existing []models.Task
+	if start, end, ok := calendarEventsRange(events); ok {
+		existing, err = database.GetTasksInRange(userId, start, end)
+		if err != nil {
+			logger.Logger.Error("Error getting tasks", zap.Int("userId", userId), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	report := models.CalendarImportReport{DryRun: dryRun}
+	report.Entries = importer.PlanCalendarImport(userId, events, rules, existing, options)
+
+	var toImport []int
+	for i, entry := range report.Entries {
+		switch entry.Status {
+		case models.ImportStatusNew:
+			report.New++
+			toImport = append(toImport, i)
+		case models.ImportStatusConflict:
+			report.Conflicts++
+			if onConflict == onConflictImport {
+				toImport = append(toImport, i)
+			}
+		case models.ImportStatusSkipped:
+			report.Skipped++
+		}
+	}
+
+	if dryRun {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	if report.Conflicts > 0 && onConflict == onConflictFail {
+		logger.Logger.Warn("Calendar import has conflicts", zap.Int("userId", userId), zap.Int("conflicts", report.Conflicts))
+		writeJSON(w, http.StatusConflict, report)
+		return
+	}
+
+	tasks := make([]models.Task, 0, len(toImport))
+	for _, i := range toImport {
+		tasks = append(tasks, report.Entries[i].Task)
+	}
+	ids, err := database.ImportTasks(actorFromRequest(r), tasks)
+	if err != nil {
+		logger.Logger.Error("Error importing calendar", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error importing calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for n, i := range toImport {
+		report.Entries[i].Task.TaskID = ids[n]
+		report.Entries[i].Status = models.ImportStatusImported
+	}
+	report.Imported = len(ids)
+
+	status := http.StatusOK
+	if report.Imported > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, report)
+	logger.Logger.Info("Calendar imported", zap.Int("userId", userId), zap.Int("imported", report.Imported))
+}
+
+func readCalendarUpload(w http.ResponseWriter, r *http.Request) ([]importer.CalendarEvent, []importer.CalendarRule, error) {
+	file, err := uploadedFile(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var ruleList []models.CalendarImportRule
+	if rulesJSON := r.FormValue("rules"); rulesJSON != "" {
+		if err := json.Unmarshal([]byte(rulesJSON), &ruleList); err != nil {
+			return nil, nil, fmt.Errorf("Invalid rules: %v", err)
+		}
+	}
+	rules, err := importer.CompileRules(ruleList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid rules: %v", err)
+	}
+
+	events, err := importer.ParseCalendar(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid calendar: %v", err)
+	}
+	return events, rules, nil
+}
+
+// calendarEventsRange returns the period covered by the importable events, in the
+// server's zone like the stored task times.
+func calendarEventsRange(events []importer.CalendarEvent) (time.Time, time.Time, bool) {
+	var start, end time.Time
+	for _, event := range events {
+		if event.SkipReason != "" {
+			continue
+		}
+		if start.IsZero() || event.Start.Before(start) {
+			start = event.Start
+		}
+		if end.IsZero() || event.End.After(end) {
+			end = event.End
+		}
+	}
+	return start.In(time.Local), end.In(time.Local), !start.IsZero()
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+)
+
+// setETag tags a user or time entry with its version. The version is also returned in
+// the version field, so a client can send If-Match for a user taken from a list.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion returns the version a change is conditional on, read from the
+// If-Match header. When the header is optional, its absence and "*" give 0, which
+// makes the change unconditional. A required header must name a version: without one,
+// "*" included, the request is answered with 428, as "*" would only check that the
+// object exists and would let a lost update through. Tags that are not versions of
+// ours can never match; a header with no other tags is answered with 412.
+//
+// A list of tags, as in If-Match: "3", "4", matches any of the versions listed. When
+// it names more than one, current looks up the version of the object and that one is
+// returned if listed; otherwise the first is, and the change fails with 412.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request, required bool, current func() (int, error)) (int, bool) {
+	value := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if value == "" || value == "*" {
+		if !required {
+			return 0, true
+		}
+		logger.Logger.Warn("If-Match header does not name a version", zap.String("ifMatch", value))
+		http.Error(w, "If-Match header with the current ETag is required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(value, ",") {
+		if version, ok := parseVersionTag(strings.TrimSpace(tag)); ok {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		logger.Logger.Warn("Invalid If-Match header", zap.String("ifMatch", value))
+		http.Error(w, fmt.Sprintf("If-Match %s does not match the current version", value), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	if len(versions) == 1 {
+		return versions[0], true
+	}
+
+	// A failed lookup is left to the change itself, which reports a missing object.
+	if version, err := current(); err == nil {
+		for _, listed := range versions {
+			if listed == version {
+				return version, true
+			}
+		}
+	}
+	return versions[0], true
+}
+
+// parseVersionTag returns the version an entity tag stands for. If-Match uses the
+// strong comparison, so weak tags never match.
+func parseVersionTag(tag string) (int, bool) {
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// userVersion and taskVersion look up the current version for an If-Match list.
+func userVersion(userId int) func() (int, error) {
+	return func() (int, error) {
+		user, err := database.GetUser(userId)
+		return user.Version, err
+	}
+}
+
+func taskVersion(taskID int) func() (int, error) {
+	return func() (int, error) {
+		task, err := database.GetTask(taskID)
+		return task.Version, err
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time-tracker/internal/logger"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	currentIs := func(version int) func() (int, error) {
+		return func() (int, error) { return version, nil }
+	}
+	notFound := func() (int, error) { return 0, errors.New("not found") }
+
+	tests := []struct {
+		name     string
+		ifMatch  []string
+		required bool
+		current  func() (int, error)
+		version  int
+		status   int
+	}{
+		{"absent", nil, false, nil, 0, http.StatusOK},
+		{"star", []string{"*"}, false, nil, 0, http.StatusOK},
+		{"absent required", nil, true, nil, 0, http.StatusPreconditionRequired},
+		{"star required", []string{"*"}, true, nil, 0, http.StatusPreconditionRequired},
+		{"version", []string{`"3"`}, true, nil, 3, http.StatusOK},
+		{"weak", []string{`W/"3"`}, true, nil, 0, http.StatusPreconditionFailed},
+		{"unquoted", []string{`3`}, true, nil, 0, http.StatusPreconditionFailed},
+		{"foreign", []string{`"abc"`}, true, nil, 0, http.StatusPreconditionFailed},
+		{"zero", []string{`"0"`}, true, nil, 0, http.StatusPreconditionFailed},
+		{"list with the current version", []string{`"3", "4"`}, true, currentIs(4), 4, http.StatusOK},
+		{"list without the current version", []string{`"3", "4"`}, true, currentIs(5), 3, http.StatusOK},
+		{"list across header lines", []string{`"3"`, `"4"`}, true, currentIs(4), 4, http.StatusOK},
+		{"list with foreign tags", []string{`W/"3", "x", "4"`}, true, nil, 4, http.StatusOK},
+		{"list of foreign tags", []string{`W/"3", "x"`}, true, nil, 0, http.StatusPreconditionFailed},
+		{"list of a missing object", []string{`"3", "4"`}, true, notFound, 3, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", nil)
+		for _, value := range tt.ifMatch {
+			r.Header.Add("If-Match", value)
+		}
+		w := httptest.NewRecorder()
+		current := tt.current
+		if current == nil {
+			current = func() (int, error) {
+				t.Errorf("%s: looked up the current version for a single tag", tt.name)
+				return 0, nil
+			}
+		}
+
+		version, ok := ifMatchVersion(w, r, tt.required, current)
+		if ok != (tt.status == http.StatusOK) || version != tt.version || w.Code != tt.status {
+			t.Errorf("%s: got version %d, ok %v, status %d, want %d with status %d", tt.name, version, ok, w.Code, tt.version, tt.status)
+		}
+	}
+}
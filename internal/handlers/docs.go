@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"time-tracker/internal/docs"
+	"time-tracker/internal/logger"
+)
+
+// GetOpenAPISpec serves the OpenAPI description of the API.
+func GetOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(docs.Spec); err != nil {
+		logger.Logger.Error("Failed to write OpenAPI spec", zap.Error(err))
+	}
+}
+
+// GetDocs serves the page that renders the OpenAPI description.
+func GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(docs.UI); err != nil {
+		logger.Logger.Error("Failed to write API docs", zap.Error(err))
+	}
+}
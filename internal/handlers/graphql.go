@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"time-tracker/internal/graphqlapi"
+	"time-tracker/internal/logger"
+)
+
+// GraphQL answers a GraphQL query. Like other GraphQL servers it answers 200 when the
+// query could be run, with any errors in the errors field of the response.
+func GraphQL(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GraphQL handler called")
+	defer logger.Logger.Info("GraphQL handler finished")
+
+	var req graphqlapi.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		logger.Logger.Warn("Empty GraphQL query")
+		http.Error(w, "GraphQL query is required", http.StatusBadRequest)
+		return
+	}
+
+	resp := graphqlapi.Execute(r.Context(), req)
+	if len(resp.Errors) > 0 {
+		logger.Logger.Warn("GraphQL query failed", zap.Any("errors", resp.Errors))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
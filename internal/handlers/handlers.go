@@ -2,147 +2,220 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"html/template"
+	"io"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 	"time-tracker/internal/database"
 	"time-tracker/internal/logger"
 	"time-tracker/internal/models"
+	"time-tracker/internal/pii"
+	"time-tracker/internal/ratelimit"
+	"time-tracker/internal/service"
+	"time-tracker/internal/templates"
+	"time-tracker/internal/validation"
 )
 
-var usersTemplate = template.Must(template.ParseFiles("internal/templates/user.html"))
-var usersEffortTemplate = template.Must(template.ParseFiles("internal/templates/user_efforts.html"))
+var usersTemplate = template.Must(template.ParseFS(templates.FS, "user.html"))
+var usersEffortTemplate = template.Must(template.ParseFS(templates.FS, "user_efforts.html"))
 
 func AddUser(w http.ResponseWriter, r *http.Request) {
 	logger.Logger.Info("AddUser handler called")
+	if _, ok := addUser(w, r); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf("User added successfully")))
+}
+
+// addUser creates a user from the passport number in the request body, filling in the
+// rest from the people info service. On failure it has already written the response.
+func addUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
 	var userReq models.UserRequest
 	err := json.NewDecoder(r.Body).Decode(&userReq)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid request body"), http.StatusBadRequest)
 		logger.Logger.Error("Invalid request body", zap.Error(err))
-		return
+		return models.User{}, false
 	}
 
-	passport := userReq.PassportNumber
+	passport, passportField := userReq.Passport()
+	user, err := service.CreateUser(actorFromRequest(r), passportField, passport)
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		logger.Logger.Error("Invalid passport number format", zap.String("passport", pii.Redact(passport)))
+		writeValidationErrors(w, fieldErrs)
+		return models.User{}, false
+	}
+	if errors.Is(err, service.ErrUserExists) {
+		http.Error(w, fmt.Sprintf("User with passport %s already exists", user.PassportNumber), http.StatusConflict)
+		logger.Logger.Error("User already exists", zap.String("passport", pii.Redact(user.PassportNumber)))
+		return models.User{}, false
+	}
+	var limitErr *ratelimit.ExceededError
+	if errors.As(err, &limitErr) {
+		ratelimit.Reject(w, limitErr)
+		logger.Logger.Warn("External API rate limit reached", zap.Duration("retryAfter", limitErr.RetryAfter))
+		return models.User{}, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed adding user: %s", err), http.StatusInternalServerError)
+		logger.Logger.Error("Failed adding user", zap.Error(err))
+		return models.User{}, false
+	}
 
-	parts := strings.Fields(passport)
+	logger.Logger.Info("User added successfully", zap.String("passport", pii.Redact(user.PassportNumber)))
+	return user, true
+}
+
+func GetUsers(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetUsers handler called")
+	defer logger.Logger.Info("GetUsers handler finished")
 
-	if len(parts) != 2 {
-		http.Error(w, fmt.Sprintf("Invalid passport number format: %s", passport), http.StatusBadRequest)
-		logger.Logger.Error("Invalid passport number format", zap.String("passport", passport))
+	users, info, ok := listUsers(w, r)
+	if !ok {
 		return
 	}
 
-	exist, err := database.CheckUserByPassport(passport)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := usersTemplate.Execute(w, usersPage{Users: users, Page: info})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error checking user: %s", err), http.StatusInternalServerError)
-		logger.Logger.Error("Error checking user", zap.Error(err))
+		logger.Logger.Error("Failed to render template", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Failed to render template: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if exist {
-		http.Error(w, fmt.Sprintf("User with passport %s already exists", passport), http.StatusConflict)
-		logger.Logger.Error("User already exists", zap.String("passport", passport))
-		return
+}
+
+// listUsers reads the filter and page from the query string and returns the page of
+// users. On failure it has already written the response.
+func listUsers(w http.ResponseWriter, r *http.Request) ([]models.User, models.PageInfo, bool) {
+	query := r.URL.Query()
+
+	filter := models.UserFilter{
+		Surname:        query.Get("surname"),
+		Name:           query.Get("name"),
+		Patronymic:     query.Get("patronymic"),
+		Address:        query.Get("address"),
+		PassportNumber: query.Get("passportNumber"),
+		Team:           query.Get("team"),
+		Match:          query.Get("match"),
+		Query:          query.Get("q"),
+		SortBy:         query.Get("sortBy"),
 	}
 
-	apiURL := os.Getenv("API_URL")
-	params := url.Values{}
-	params.Add("passportSerie", parts[0])
-	params.Add("passportNumber", parts[1])
+	for _, idsString := range query["ids"] {
+		for _, idString := range strings.Split(idsString, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idString))
+			if err != nil || id < 1 {
+				logger.Logger.Warn("Invalid user ID in ids filter", zap.String("idString", idString), zap.Error(err))
+				http.Error(w, fmt.Sprintf("Invalid user ID: %v", idString), http.StatusBadRequest)
+				return nil, models.PageInfo{}, false
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
 
-	resp, err := http.Get(fmt.Sprintf("%s?%s", apiURL, params.Encode()))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("Failed to get user info from externalAPI: %s", err), http.StatusInternalServerError)
-		logger.Logger.Error("Failed to get user info from externalAPI", zap.Error(err))
-		return
+	switch sortOrder := query.Get("sortOrder"); strings.ToLower(sortOrder) {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		logger.Logger.Warn("Invalid sort order", zap.String("sortOrder", sortOrder))
+		http.Error(w, fmt.Sprintf("Invalid sort order: %s", sortOrder), http.StatusBadRequest)
+		return nil, models.PageInfo{}, false
 	}
-	defer resp.Body.Close()
 
-	apiUser := models.User{PassportNumber: passport}
-	err = json.NewDecoder(resp.Body).Decode(&apiUser)
+	page, err := parsePageRequest(query)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed parsing user info from externalAPI: %s", err), http.StatusInternalServerError)
-		logger.Logger.Error("Failed parsing user info from externalAPI", zap.Error(err))
-		return
+		logger.Logger.Warn("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, models.PageInfo{}, false
+	}
+	if page.Cursor != nil {
+		filter.SortBy = page.Cursor.SortBy
+		filter.SortDesc = page.Cursor.Desc
 	}
 
-	err = database.SaveUser(apiUser)
+	result, err := service.ListUsers(filter, page)
+	if errors.Is(err, service.ErrInvalidFilter) || errors.Is(err, database.ErrInvalidCursor) {
+		logger.Logger.Warn("Invalid users filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, models.PageInfo{}, false
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed saving user: %s", err), http.StatusInternalServerError)
-		logger.Logger.Error("Failed saving user", zap.Error(err))
-		return
+		logger.Logger.Error("Error getting users from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting users: %v", err), http.StatusInternalServerError)
+		return nil, models.PageInfo{}, false
 	}
+	users := result.Users
 
-	logger.Logger.Info("User added successfully", zap.String("passport", passport))
+	userCursor := func(user models.User) models.Cursor {
+		cursor := models.Cursor{SortBy: filter.SortBy, Desc: filter.SortDesc, ID: user.ID}
+		// Encrypted fields are not put in URLs; the database finds the value by the ID.
+		if !models.UserDecryptedSortFields[filter.SortBy] {
+			cursor.Value = user.SortValue(filter.SortBy)
+		}
+		return cursor
+	}
+	info := pageInfo(w, r, page, result.Total, len(users), result.More,
+		func() models.Cursor { return userCursor(users[0]) },
+		func() models.Cursor { return userCursor(users[len(users)-1]) })
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf("User added successfully")))
+	return users, info, true
 }
 
-func GetUsers(w http.ResponseWriter, r *http.Request) {
-	logger.Logger.Info("GetUsers handler called")
-	defer logger.Logger.Info("GetUsers handler finished")
-
-	query := r.URL.Query()
-
-	pageStr := query.Get("page")
-	pageSizeStr := query.Get("pageSize")
+type usersPage struct {
+	Users []models.User
+	Page  models.PageInfo
+}
 
-	surname := query.Get("surname")
-	name := query.Get("name")
-	patronymic := query.Get("patronymic")
-	address := query.Get("address")
-	passportNumber := query.Get("passportNumber")
+func GetWorkLog(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetWorkLog handler called")
+	defer logger.Logger.Info("GetWorkLog handler finished")
 
-	page, err := strconv.Atoi(pageStr)
-	if page < 1 || err != nil {
-		page = 1
-		if err != nil {
-			logger.Logger.Warn("Invalid page number, defaulting to 1", zap.String("pageStr", pageStr), zap.Error(err))
-		} else {
-			logger.Logger.Warn("Page number less than 1, defaulting to 1", zap.String("pageStr", pageStr))
-		}
+	filter, ok := parseTaskFilter(w, r)
+	if !ok {
+		return
 	}
 
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if pageSize < 1 || err != nil {
-		pageSize = 10
-		if err != nil {
-			logger.Logger.Warn("Invalid page size, defaulting to 10", zap.String("pageSizeStr", pageSizeStr), zap.Error(err))
-		} else {
-			logger.Logger.Warn("Page size less than 1, defaulting to 10", zap.String("pageSizeStr", pageSizeStr))
-		}
+	format, err := reportFormat(r)
+	if err != nil {
+		logger.Logger.Warn("Invalid report format", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format != formatHTML {
+		exportWorkLog(w, r, format, filter)
+		return
 	}
 
-	offset := (page - 1) * pageSize
-
-	users, err := database.GetUsers(pageSize, offset, name, surname, patronymic, passportNumber, address)
-	if err != nil {
-		logger.Logger.Error("Error getting users from database", zap.Error(err))
-		http.Error(w, fmt.Sprintf("Error getting users: %v", err), http.StatusInternalServerError)
+	tasks, info, ok := listTasks(w, r, filter)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	err = usersTemplate.Execute(w, users)
+	// Tasks come ordered by duration, so the efforts keep the descending order.
+	userEfforts := models.CalculateUserEffort(tasks)
+
+	w.Header().Set("Content-Type", "text/html")
+	err = usersEffortTemplate.Execute(w, userEffortsPage{Efforts: userEfforts, Page: info})
 	if err != nil {
-		logger.Logger.Error("Failed to render template", zap.Error(err))
-		http.Error(w, fmt.Sprintf("Failed to render template: %v", err), http.StatusInternalServerError)
+		logger.Logger.Error("Error executing template", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error executing template: %v", err), http.StatusInternalServerError)
 		return
 	}
 }
 
-func GetWorkLog(w http.ResponseWriter, r *http.Request) {
-	logger.Logger.Info("GetWorkLog handler called")
-	defer logger.Logger.Info("GetWorkLog handler finished")
-
+// parseTaskFilter reads the user from the path and the optional period from the query
+// string. On failure it has already written the response.
+func parseTaskFilter(w http.ResponseWriter, r *http.Request) (models.TaskFilter, bool) {
 	userIdString := chi.URLParam(r, "id")
 	startPeriodString := r.URL.Query().Get("startPeriod")
 	endPeriodString := r.URL.Query().Get("endPeriod")
@@ -151,51 +224,71 @@ func GetWorkLog(w http.ResponseWriter, r *http.Request) {
 	if err != nil || userId < 1 {
 		logger.Logger.Warn("Invalid user id", zap.String("userIdString", userIdString), zap.Error(err))
 		http.Error(w, fmt.Sprintf("Invalid user id: %v", err), http.StatusBadRequest)
-		return
+		return models.TaskFilter{}, false
 	}
 
-	var tasks []models.Task
+	filter := models.TaskFilter{UserID: userId}
 
-	if startPeriodString == "" || endPeriodString == "" {
-		tasks, err = database.GetTasks(userId)
-		if err != nil {
-			logger.Logger.Error("Error getting tasks without period", zap.Error(err))
-			http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
-			return
-		}
-	} else {
-		startPeriod, err := time.Parse(time.RFC3339, startPeriodString)
+	if startPeriodString != "" && endPeriodString != "" {
+		filter.Start, err = time.Parse(time.RFC3339, startPeriodString)
 		if err != nil {
 			logger.Logger.Warn("Invalid start period format", zap.String("startPeriodString", startPeriodString), zap.Error(err))
 			http.Error(w, "Invalid start period format", http.StatusBadRequest)
-			return
+			return models.TaskFilter{}, false
 		}
 
-		endPeriod, err := time.Parse(time.RFC3339, endPeriodString)
+		filter.End, err = time.Parse(time.RFC3339, endPeriodString)
 		if err != nil {
 			logger.Logger.Warn("Invalid end period format", zap.String("endPeriodString", endPeriodString), zap.Error(err))
 			http.Error(w, "Invalid end period format", http.StatusBadRequest)
-			return
+			return models.TaskFilter{}, false
 		}
+	}
+	return filter, true
+}
 
-		tasks, err = database.GetTasksByPeriod(userId, startPeriod, endPeriod)
-		if err != nil {
-			logger.Logger.Error("Error getting tasks by period", zap.Error(err))
-			http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
-			return
-		}
+// listTasks returns a page of the time entries matching the filter, longest first. On
+// failure it has already written the response.
+func listTasks(w http.ResponseWriter, r *http.Request, filter models.TaskFilter) ([]models.Task, models.PageInfo, bool) {
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		logger.Logger.Warn("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, models.PageInfo{}, false
 	}
 
-	userEfforts := models.CalculateUserEffort(tasks)
-	models.SortUserEfforts(userEfforts)
+	tasks, more, err := database.GetTasksPage(filter, page)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		logger.Logger.Warn("Invalid tasks cursor", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, models.PageInfo{}, false
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting tasks", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+		return nil, models.PageInfo{}, false
+	}
 
-	w.Header().Set("Content-Type", "text/html")
-	err = usersEffortTemplate.Execute(w, userEfforts)
+	total, err := database.CountTasks(filter)
 	if err != nil {
-		logger.Logger.Error("Error executing template", zap.Error(err))
-		http.Error(w, fmt.Sprintf("Error executing template: %v", err), http.StatusInternalServerError)
-		return
+		logger.Logger.Error("Error counting tasks", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+		return nil, models.PageInfo{}, false
 	}
+
+	taskCursor := func(task models.Task) models.Cursor {
+		duration := task.EndTime.Sub(task.StartTime)
+		return models.Cursor{Value: fmt.Sprintf("%d microseconds", duration.Microseconds()), ID: task.TaskID}
+	}
+	info := pageInfo(w, r, page, total, len(tasks), more,
+		func() models.Cursor { return taskCursor(tasks[0]) },
+		func() models.Cursor { return taskCursor(tasks[len(tasks)-1]) })
+	return tasks, info, true
+}
+
+type userEffortsPage struct {
+	Efforts []models.UserEffort
+	Page    models.PageInfo
 }
 
 func StartTask(w http.ResponseWriter, r *http.Request) {
@@ -211,7 +304,15 @@ func StartTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = database.StartTaskTimer(userID)
+	var taskReq models.TaskRequest
+	err = json.NewDecoder(r.Body).Decode(&taskReq)
+	if err != nil && !errors.Is(err, io.EOF) {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := service.StartTimer(actorFromRequest(r), userID, taskReq)
 	if err != nil {
 		logger.Logger.Error("Error starting task", zap.Error(err))
 		http.Error(w, fmt.Sprintf("Error starting task: %v", err), http.StatusInternalServerError)
@@ -219,8 +320,8 @@ func StartTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Task-Timer started"))
-	logger.Logger.Info("Task-Timer started successfully", zap.Int("userID", userID))
+	w.Write([]byte(fmt.Sprintf("Task-Timer started: %d", taskID)))
+	logger.Logger.Info("Task-Timer started successfully", zap.Int("userID", userID), zap.Int("taskID", taskID))
 }
 
 func StopTask(w http.ResponseWriter, r *http.Request) {
@@ -249,7 +350,22 @@ func StopTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = database.StopTaskTimer(taskID)
+	version, ok := ifMatchVersion(w, r, false, taskVersion(taskID))
+	if !ok {
+		return
+	}
+
+	err = service.StopTimer(actorFromRequest(r), taskID, version)
+	if errors.Is(err, database.ErrTimerStopped) {
+		logger.Logger.Warn("Task is already stopped", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d not exist", taskID), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("Task has been modified", zap.Int("taskID", taskID))
+		http.Error(w, fmt.Sprintf("Task with id %d has been modified", taskID), http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		logger.Logger.Error("Error stopping task timer", zap.Error(err))
 		http.Error(w, fmt.Sprintf("Error stopping task: %v", err), http.StatusInternalServerError)
@@ -273,7 +389,26 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = database.DeleteUser(userId)
+	version, ok := ifMatchVersion(w, r, false, userVersion(userId))
+	if !ok {
+		return
+	}
+	if version == 0 {
+		// Without If-Match the legacy route changes the user whatever its version.
+		version = service.AnyVersion
+	}
+
+	err = service.DeleteUser(actorFromRequest(r), userId, version)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("User has been modified", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d has been modified", userId), http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		logger.Logger.Error("Error deleting user", zap.Int("userId", userId), zap.Error(err))
 		http.Error(w, fmt.Sprintf("Error deleting user: %v", err), http.StatusInternalServerError)
@@ -310,21 +445,85 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Query parameters cannot express null, so empty ones leave the field unchanged.
+	var update models.UserUpdate
 	query := r.URL.Query()
-	surname := query.Get("surname")
-	name := query.Get("name")
-	patronymic := query.Get("patronymic")
-	address := query.Get("address")
-	passportNumber := query.Get("passportNumber")
-
-	err = database.UpdateUser(userId, surname, name, patronymic, passportNumber, address)
-	if err != nil {
-		logger.Logger.Error("Error updating user", zap.Int("userId", userId), zap.Error(err))
-		http.Error(w, fmt.Sprintf("Error updating user: %v", err), http.StatusInternalServerError)
+	for param, field := range map[string]*models.OptionalString{
+		"surname":        &update.Surname,
+		"name":           &update.Name,
+		"patronymic":     &update.Patronymic,
+		"address":        &update.Address,
+		"passportNumber": &update.PassportNumber,
+		"team":           &update.Team,
+	} {
+		if value := query.Get(param); value != "" {
+			*field = models.OptionalString{Set: true, Value: value}
+		}
+	}
+	user, ok := updateUser(w, r, userId, update, "passportNumber", false)
+	if !ok {
 		return
 	}
+	setETag(w, user.Version)
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("User updated"))
 	logger.Logger.Info("User updated successfully", zap.Int("userId", userId))
 }
+
+// updateUser validates and applies the update and returns the updated user. An update
+// with no fields is rejected with 400. ifMatchRequired answers a request without
+// If-Match with 428; the legacy route keeps accepting unconditional updates.
+// passportField names the passport number in validation errors, as it is spelled
+// differently in query strings and JSON bodies. On failure it has already written the
+// response.
+func updateUser(w http.ResponseWriter, r *http.Request, userId int, update models.UserUpdate, passportField string, ifMatchRequired bool) (models.User, bool) {
+	version, ok := ifMatchVersion(w, r, ifMatchRequired, userVersion(userId))
+	if !ok {
+		return models.User{}, false
+	}
+	if version == 0 {
+		// Without If-Match the legacy route changes the user whatever its version.
+		version = service.AnyVersion
+	}
+
+	user, err := service.UpdateUser(actorFromRequest(r), userId, version, update, passportField)
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		logger.Logger.Warn("Invalid user update", zap.Int("userId", userId), zap.Error(fieldErrs))
+		writeValidationErrors(w, fieldErrs)
+		return user, false
+	}
+	if errors.Is(err, database.ErrEmptyUpdate) {
+		logger.Logger.Warn("Empty user update", zap.Int("userId", userId))
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return user, false
+	}
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return user, false
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("User has been modified", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d has been modified", userId), http.StatusPreconditionFailed)
+		return user, false
+	}
+	if errors.Is(err, service.ErrUserExists) {
+		logger.Logger.Warn("Passport number belongs to another user", zap.Int("userId", userId),
+			zap.String("passport", pii.Redact(user.PassportNumber)))
+		http.Error(w, fmt.Sprintf("User with passport %s already exists", user.PassportNumber), http.StatusConflict)
+		return user, false
+	}
+	if errors.Is(err, database.ErrUserAnonymized) {
+		logger.Logger.Warn("User is anonymized", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d is anonymized and cannot be changed", userId), http.StatusConflict)
+		return user, false
+	}
+	if err != nil {
+		logger.Logger.Error("Error updating user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error updating user: %v", err), http.StatusInternalServerError)
+		return user, false
+	}
+	return user, true
+}
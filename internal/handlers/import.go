@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time-tracker/internal/export"
+	"time-tracker/internal/importer"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// ImportUsers creates users in bulk from an uploaded CSV file.
+func ImportUsers(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ImportUsers handler called")
+	defer logger.Logger.Info("ImportUsers handler finished")
+
+	runCSVImport(w, r, importer.ImportUsersCSV)
+}
+
+// ImportTimeEntries creates completed time entries in bulk from an uploaded CSV file.
+func ImportTimeEntries(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ImportTimeEntries handler called")
+	defer logger.Logger.Info("ImportTimeEntries handler finished")
+
+	runCSVImport(w, r, importer.ImportTimeEntriesCSV)
+}
+
+type csvImportFunc func(r io.Reader, options importer.CSVImportOptions) (models.BulkImportReport, error)
+
+func runCSVImport(w http.ResponseWriter, r *http.Request, importCSV csvImportFunc) {
+	query := r.URL.Query()
+	options := importer.CSVImportOptions{Actor: actorFromRequest(r)}
+
+	var err error
+	options.Dialect, err = export.ParseCSVOptions(query.Get("delimiter"), query.Get("encoding"))
+	if err != nil {
+		logger.Logger.Warn("Invalid CSV options", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.DryRun, err = parseBoolParam(query, "dryRun")
+	if err != nil {
+		logger.Logger.Warn("Invalid dry run flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.SkipEnrichment, err = parseBoolParam(query, "skipEnrichment")
+	if err != nil {
+		logger.Logger.Warn("Invalid skip enrichment flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := uploadedFile(w, r)
+	if err != nil {
+		logger.Logger.Warn("Invalid upload", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// The mapping may come in the query string or, with a multipart upload, in the form.
+	options.Columns, err = importer.ParseColumnMapping(r.FormValue("columns"))
+	if err != nil {
+		logger.Logger.Warn("Invalid column mapping", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := importCSV(file, options)
+	if errors.Is(err, importer.ErrInvalidFile) {
+		logger.Logger.Warn("Invalid import file", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error importing CSV", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error importing CSV: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Imported > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, report)
+	logger.Logger.Info("CSV import finished", zap.Bool("dryRun", report.DryRun), zap.Int("total", report.Total),
+		zap.Int("imported", report.Imported), zap.Int("invalid", report.Invalid))
+}
+
+// ImportTrackerExport imports time entries from a Toggl Track, Clockify or Harvest
+// export (CSV or JSON), named by the source URL parameter.
+func ImportTrackerExport(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ImportTrackerExport handler called")
+	defer logger.Logger.Info("ImportTrackerExport handler finished")
+
+	query := r.URL.Query()
+	source := chi.URLParam(r, "source")
+	options := importer.TrackerImportOptions{Actor: actorFromRequest(r)}
+
+	var err error
+	options.DryRun, err = parseBoolParam(query, "dryRun")
+	if err != nil {
+		logger.Logger.Warn("Invalid dry run flag", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.Parse, err = importer.ParseTrackerOptions(query.Get("timezone"), query.Get("dayStart"))
+	if err != nil {
+		logger.Logger.Warn("Invalid import options", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := uploadedFile(w, r)
+	if err != nil {
+		logger.Logger.Warn("Invalid upload", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	options.Users, err = importer.ParseUserMapping(r.FormValue("users"))
+	if err != nil {
+		logger.Logger.Warn("Invalid user mapping", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := importer.ImportTrackerExport(source, file, options)
+	if errors.Is(err, importer.ErrInvalidFile) || errors.Is(err, importer.ErrUnsupportedSource) {
+		logger.Logger.Warn("Invalid import file", zap.String("source", source), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error importing tracker export", zap.String("source", source), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error importing %s export: %v", source, err), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Imported > 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, report)
+	logger.Logger.Info("Tracker export imported", zap.String("source", source), zap.Bool("dryRun", report.DryRun),
+		zap.Int("total", report.Total), zap.Int("imported", report.Imported), zap.Int("invalid", report.Invalid))
+}
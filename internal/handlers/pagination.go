@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// parsePageRequest reads page/pageSize for offset paging, or cursor for keyset paging.
+// An empty cursor parameter requests the first page in keyset mode. Page sizes above
+// models.MaxPageSize are capped.
+func parsePageRequest(query url.Values) (models.PageRequest, error) {
+	pageStr := query.Get("page")
+	pageSizeStr := query.Get("pageSize")
+
+	page, err := strconv.Atoi(pageStr)
+	if page < 1 || err != nil {
+		page = 1
+		if err != nil {
+			logger.Logger.Warn("Invalid page number, defaulting to 1", zap.String("pageStr", pageStr), zap.Error(err))
+		} else {
+			logger.Logger.Warn("Page number less than 1, defaulting to 1", zap.String("pageStr", pageStr))
+		}
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if pageSize < 1 || err != nil {
+		pageSize = 10
+		if err != nil {
+			logger.Logger.Warn("Invalid page size, defaulting to 10", zap.String("pageSizeStr", pageSizeStr), zap.Error(err))
+		} else {
+			logger.Logger.Warn("Page size less than 1, defaulting to 10", zap.String("pageSizeStr", pageSizeStr))
+		}
+	}
+
+	if pageSize > models.MaxPageSize {
+		logger.Logger.Warn("Page size too large, capping it", zap.Int("pageSize", pageSize), zap.Int("maxPageSize", models.MaxPageSize))
+	}
+
+	pageReq := models.OffsetPage(page, pageSize)
+	if !query.Has("cursor") {
+		return pageReq, nil
+	}
+
+	pageReq.Keyset = true
+	pageReq.Offset = 0
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := decodeCursor(cursorStr)
+		if err != nil {
+			return pageReq, err
+		}
+		pageReq.Cursor = &cursor
+	}
+	return pageReq, nil
+}
+
+func encodeCursor(cursor models.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursorStr string) (models.Cursor, error) {
+	var cursor models.Cursor
+	data, err := base64.RawURLEncoding.DecodeString(cursorStr)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// pageInfo builds the total count and next/prev links of a listing page and mirrors
+// them in the X-Total-Count and Link headers. first and last return the keyset
+// position of the first and last row on the page.
+func pageInfo(w http.ResponseWriter, r *http.Request, page models.PageRequest, total, count int, more bool,
+	first, last func() models.Cursor) models.PageInfo {
+	info := models.PageInfo{Total: total}
+
+	if page.Keyset {
+		backward := page.Cursor != nil && page.Cursor.Backward
+		if count > 0 && (more || backward) {
+			cursor := last()
+			cursor.Backward = false
+			info.Next = pageLink(r, "cursor", encodeCursor(cursor))
+		}
+		if count > 0 && ((backward && more) || (!backward && page.Cursor != nil)) {
+			cursor := first()
+			cursor.Backward = true
+			info.Prev = pageLink(r, "cursor", encodeCursor(cursor))
+		}
+	} else {
+		current := page.Offset/page.Limit + 1
+		if page.Offset+count < total {
+			info.Next = pageLink(r, "page", strconv.Itoa(current+1))
+		}
+		if current > 1 {
+			info.Prev = pageLink(r, "page", strconv.Itoa(current-1))
+		}
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(info.Total))
+	var links []string
+	if info.Next != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, info.Next))
+	}
+	if info.Prev != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, info.Prev))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return info
+}
+
+func pageLink(r *http.Request, param, value string) string {
+	query := r.URL.Query()
+	if param == "cursor" {
+		query.Del("page")
+	}
+	query.Set(param, value)
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"go.uber.org/zap"
+	"net/url"
+	"testing"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []models.Cursor{
+		{ID: 1},
+		{SortBy: "duration", Desc: true, Value: "5400000000 microseconds", ID: 42},
+		{SortBy: "surname", Value: "Иванов", ID: 7, Backward: true},
+	}
+	for _, cursor := range tests {
+		encoded := encodeCursor(cursor)
+		got, err := decodeCursor(encoded)
+		if err != nil {
+			t.Errorf("decodeCursor(encodeCursor(%+v)): %v", cursor, err)
+			continue
+		}
+		if got != cursor {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", cursor, got)
+		}
+	}
+}
+
+func TestDecodeCursorMalformed(t *testing.T) {
+	for _, cursor := range []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte(`{"i":1}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"i":`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"i":"1"}`)),
+	} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded, want an error", cursor)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	cursor := models.Cursor{SortBy: "surname", Value: "Петров", ID: 3}
+
+	tests := []struct {
+		query   string
+		want    models.PageRequest
+		wantErr bool
+	}{
+		{"", models.PageRequest{Limit: 10}, false},
+		{"page=3&pageSize=20", models.PageRequest{Limit: 20, Offset: 40}, false},
+		{"page=0&pageSize=abc", models.PageRequest{Limit: 10}, false},
+		{"pageSize=1000", models.PageRequest{Limit: models.MaxPageSize}, false},
+		{"page=5&cursor=", models.PageRequest{Limit: 10, Keyset: true}, false},
+		{"cursor=" + encodeCursor(cursor), models.PageRequest{Limit: 10, Keyset: true, Cursor: &cursor}, false},
+		{"cursor=%7B", models.PageRequest{}, true},
+	}
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parsePageRequest(query)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePageRequest(%q) succeeded, want an error", tt.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePageRequest(%q): %v", tt.query, err)
+			continue
+		}
+		if got.Limit != tt.want.Limit || got.Offset != tt.want.Offset || got.Keyset != tt.want.Keyset ||
+			(got.Cursor == nil) != (tt.want.Cursor == nil) || (got.Cursor != nil && *got.Cursor != *tt.want.Cursor) {
+			t.Errorf("parsePageRequest(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
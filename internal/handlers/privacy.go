@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+func ExportUserData(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("ExportUserData handler called")
+	defer logger.Logger.Info("ExportUserData handler finished")
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		logger.Logger.Warn("Invalid export format", zap.String("format", format))
+		http.Error(w, fmt.Sprintf("Invalid export format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	export := models.UserDataExport{ExportedAt: time.Now()}
+
+	export.Profile, err = database.GetUser(userId)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	export.TimeEntries, err = database.GetAllTasks(userId)
+	if err != nil {
+		logger.Logger.Error("Error getting tasks", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	export.AuditEntries, err = database.GetUserAuditEntries(userId)
+	if err != nil {
+		logger.Logger.Error("Error getting audit entries", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting audit entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = database.RecordAuditEntry(actorFromRequest(r), models.AuditUserExport, models.AuditTargetUser, userId)
+	if err != nil {
+		logger.Logger.Error("Error recording export in audit log", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error exporting user data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-export", userId)
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", contentDisposition("attachment", filename+".zip"))
+		err = writeUserDataZip(w, export)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", contentDisposition("attachment", filename+".json"))
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(export)
+	}
+	if err != nil {
+		logger.Logger.Error("Error writing user data export", zap.Int("userId", userId), zap.Error(err))
+		return
+	}
+	logger.Logger.Info("User data exported", zap.Int("userId", userId))
+}
+
+// writeUserDataZip writes the export as a ZIP bundle with one JSON file per section.
+func writeUserDataZip(w http.ResponseWriter, export models.UserDataExport) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"time_entries.json", export.TimeEntries},
+		{"audit_entries.json", export.AuditEntries},
+		{"export.json", struct {
+			ExportedAt time.Time `json:"exported_at"`
+			UserID     int       `json:"user_id"`
+		}{export.ExportedAt, export.Profile.ID}},
+	}
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func AnonymizeUser(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("AnonymizeUser handler called")
+	defer logger.Logger.Info("AnonymizeUser handler finished")
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	// The route is shared with the legacy API, whose clients send no If-Match.
+	version, ok := ifMatchVersion(w, r, strings.HasPrefix(r.URL.Path, APIPrefix), userVersion(userId))
+	if !ok {
+		return
+	}
+
+	err = database.AnonymizeUser(actorFromRequest(r), userId, version)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		logger.Logger.Warn("User has been modified", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d has been modified", userId), http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, database.ErrUserAnonymized) {
+		logger.Logger.Warn("User is already anonymized", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d is already anonymized", userId), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error anonymizing user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error anonymizing user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("User anonymized"))
+	logger.Logger.Info("User anonymized successfully", zap.Int("userId", userId))
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/export"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+const (
+	formatHTML = "html"
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+	formatJSON = "json"
+	formatPDF  = "pdf"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// reportFormat picks the report format from the format parameter, falling back to
+// the Accept header and then to HTML.
+func reportFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case formatHTML, formatCSV, formatXLSX, formatJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return formatCSV, nil
+		case xlsxContentType:
+			return formatXLSX, nil
+		case "application/json":
+			return formatJSON, nil
+		case "text/html":
+			return formatHTML, nil
+		}
+	}
+	return formatHTML, nil
+}
+
+// writeReport writes the tables as a CSV or XLSX attachment. CSV holds only the
+// first table; XLSX gets one sheet per table.
+func writeReport(w http.ResponseWriter, r *http.Request, format, filename string, tables ...export.Table) {
+	var err error
+	switch format {
+	case formatCSV:
+		options, optErr := export.ParseCSVOptions(r.URL.Query().Get("delimiter"), r.URL.Query().Get("encoding"))
+		if optErr != nil {
+			logger.Logger.Warn("Invalid CSV options", zap.Error(optErr))
+			http.Error(w, optErr.Error(), http.StatusBadRequest)
+			return
+		}
+		charset := "utf-8"
+		if options.Encoding == export.EncodingWindows1251 {
+			charset = "windows-1251"
+		}
+		w.Header().Set("Content-Type", "text/csv; charset="+charset)
+		w.Header().Set("Content-Disposition", contentDisposition("attachment", filename+".csv"))
+		err = export.WriteCSV(w, tables[0], options)
+	case formatXLSX:
+		w.Header().Set("Content-Type", xlsxContentType)
+		w.Header().Set("Content-Disposition", contentDisposition("attachment", filename+".xlsx"))
+		err = export.WriteXLSX(w, tables...)
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to write report", zap.String("format", format), zap.Error(err))
+	}
+}
+
+// contentDisposition builds a Content-Disposition header for a file. The filename is
+// quoted, or percent-encoded when it is not plain ASCII, as names built from user
+// data such as a team name may hold anything.
+func contentDisposition(disposition, filename string) string {
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// exportWorkLog writes the whole worklog for the period, without pagination, as a
+// spreadsheet-friendly report.
+func exportWorkLog(w http.ResponseWriter, r *http.Request, format string, filter models.TaskFilter) {
+	user, err := database.GetUser(filter.UserID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", filter.UserID))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", filter.UserID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting user", zap.Int("userId", filter.UserID), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var tasks []models.Task
+	if filter.Start.IsZero() {
+		tasks, err = database.GetTasks(filter.UserID)
+	} else {
+		tasks, err = database.GetTasksByPeriod(filter.UserID, filter.Start, filter.End)
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting tasks", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting tasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == formatJSON {
+		writeJSON(w, http.StatusOK, worklogJSON(filter, tasks))
+		return
+	}
+
+	worklog, summary := worklogTables(user, filter, tasks)
+	writeReport(w, r, format, fmt.Sprintf("worklog-user-%d", filter.UserID), worklog, summary)
+}
+
+// worklogJSON lists the time entries in chronological order, with times in the
+// server's zone.
+func worklogJSON(filter models.TaskFilter, tasks []models.Task) models.Worklog {
+	worklog := models.Worklog{UserID: filter.UserID, Entries: make([]models.Task, 0, len(tasks))}
+	if !filter.Start.IsZero() {
+		worklog.PeriodStart, worklog.PeriodEnd = &filter.Start, &filter.End
+	}
+	var total time.Duration
+	for _, task := range tasks {
+		task.StartTime, task.EndTime = models.WallClock(task.StartTime), models.WallClock(task.EndTime)
+		total += task.EndTime.Sub(task.StartTime)
+		worklog.Entries = append(worklog.Entries, task)
+	}
+	sort.SliceStable(worklog.Entries, func(i, j int) bool {
+		return worklog.Entries[i].StartTime.Before(worklog.Entries[j].StartTime)
+	})
+	worklog.TotalSeconds = int64(total.Seconds())
+	return worklog
+}
+
+// worklogTables builds the detailed worklog table, ordered by descending effort,
+// and a one-row summary table for the user and period.
+func worklogTables(user models.User, filter models.TaskFilter, tasks []models.Task) (export.Table, export.Table) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].EndTime.Sub(tasks[i].StartTime) > tasks[j].EndTime.Sub(tasks[j].StartTime)
+	})
+
+	worklog := export.Table{
+		Name:    "Worklog",
+		Columns: []string{"User ID", "Task ID", "Title", "Start", "End", "Hours", "Minutes", "Total hours"},
+	}
+	var total time.Duration
+	for _, task := range tasks {
+		duration := task.EndTime.Sub(task.StartTime)
+		total += duration
+		worklog.Rows = append(worklog.Rows, []interface{}{
+			task.UserID, task.TaskID, task.Title, task.StartTime, task.EndTime,
+			int(duration.Hours()), int(duration.Minutes()) % 60, duration.Hours(),
+		})
+	}
+
+	summary := export.Table{
+		Name: "Summary",
+		Columns: []string{"User ID", "Surname", "Name", "Patronymic", "Period start", "Period end",
+			"Entries", "Hours", "Minutes", "Total hours"},
+		Rows: [][]interface{}{{
+			user.ID, user.Surname, user.Name, user.Patronymic, filter.Start, filter.End,
+			len(tasks), int(total.Hours()), int(total.Minutes()) % 60, total.Hours(),
+		}},
+	}
+	return worklog, summary
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"mime"
+	"testing"
+)
+
+func TestContentDisposition(t *testing.T) {
+	for _, filename := range []string{
+		"timesheets-2024-07.zip",
+		"timesheets-Отдел продаж-2024-07.zip",
+		"timesheets-a\"b\r\nSet-Cookie: x-2024-07.zip",
+	} {
+		header := contentDisposition("attachment", filename)
+		disposition, params, err := mime.ParseMediaType(header)
+		if err != nil || disposition != "attachment" || params["filename"] != filename {
+			t.Errorf("contentDisposition(%q) = %q, which reads back as %q, %v, %v", filename, header, disposition, params, err)
+		}
+	}
+}
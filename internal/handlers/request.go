@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+)
+
+// actorFromRequest identifies who performs a mutating request for the audit log by
+// the remote IP. A name passed in the X-Actor header is recorded only as claimed, since
+// any client can send one.
+func actorFromRequest(r *http.Request) models.Actor {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return models.NewActor(host, r.Header.Get("X-Actor"))
+}
+
+// parseBoolParam reads an optional boolean query parameter, false when absent.
+func parseBoolParam(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid %s flag: %s", name, value)
+	}
+	return b, nil
+}
+
+// maxUploadSize limits the size of uploaded import files.
+const maxUploadSize = 10 << 20
+
+// uploadedFile returns the file of an import request: the "file" field of a multipart
+// form or, for any other content type, the request body. Other form fields can be read
+// with r.FormValue afterwards.
+func uploadedFile(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, fmt.Errorf("Invalid upload: %v", err)
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("The file must be uploaded in the file field: %v", err)
+	}
+	return file, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logger.Logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+type validationErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields validation.Errors `json:"fields"`
+}
+
+func writeValidationErrors(w http.ResponseWriter, errs validation.Errors) {
+	writeJSON(w, http.StatusBadRequest, validationErrorResponse{Error: "validation failed", Fields: errs})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time-tracker/internal/models"
+)
+
+func TestActorFromRequest(t *testing.T) {
+	long := strings.Repeat("я", 150)
+	tests := []struct {
+		remoteAddr string
+		xActor     string
+		want       models.Actor
+	}{
+		{"203.0.113.7:51234", "", models.Actor{Name: "203.0.113.7"}},
+		{"203.0.113.7:51234", "admin", models.Actor{Name: "203.0.113.7", Claimed: "admin"}},
+		{"[2001:db8::1]:443", "alice", models.Actor{Name: "2001:db8::1", Claimed: "alice"}},
+		{"pipe", "", models.Actor{Name: "pipe"}},
+		{"203.0.113.7:51234", long, models.Actor{Name: "203.0.113.7", Claimed: strings.Repeat("я", 100)}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.xActor != "" {
+			r.Header.Set("X-Actor", tt.xActor)
+		}
+		if got := actorFromRequest(r); got != tt.want {
+			t.Errorf("%s with X-Actor %q: got %+v, want %+v", tt.remoteAddr, tt.xActor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/search"
+)
+
+const maxSearchLimit = 100
+
+func Search(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("Search handler called")
+	defer logger.Logger.Info("Search handler finished")
+
+	query := r.URL.Query()
+
+	variants := search.Variants(query.Get("q"))
+	if len(variants) == 0 {
+		logger.Logger.Warn("Empty search query")
+		http.Error(w, "Search query q is required", http.StatusBadRequest)
+		return
+	}
+
+	searchType := query.Get("type")
+	if searchType != "" && searchType != "users" && searchType != "tasks" {
+		logger.Logger.Warn("Invalid search type", zap.String("type", searchType))
+		http.Error(w, fmt.Sprintf("Invalid search type: %s", searchType), http.StatusBadRequest)
+		return
+	}
+
+	limitStr := query.Get("limit")
+	limit, err := strconv.Atoi(limitStr)
+	if limit < 1 || err != nil {
+		limit = 20
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	results := models.SearchResults{
+		Users: []models.UserSearchResult{},
+		Tasks: []models.TaskSearchResult{},
+	}
+
+	if searchType == "" || searchType == "users" {
+		results.Users, err = database.SearchUsers(variants, limit)
+		if err != nil {
+			logger.Logger.Error("Error searching users", zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error searching users: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if searchType == "" || searchType == "tasks" {
+		results.Tasks, err = database.SearchTasks(variants, limit)
+		if err != nil {
+			logger.Logger.Error("Error searching tasks", zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error searching tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/events"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+const (
+	defaultTickInterval = 10 * time.Second
+	maxTickInterval     = 5 * time.Minute
+	// sseRetry tells EventSource clients how long to wait before reconnecting.
+	sseRetry = 5 * time.Second
+)
+
+// GetRunningTimers returns the timers running right now.
+func GetRunningTimers(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetRunningTimers handler called")
+	defer logger.Logger.Info("GetRunningTimers handler finished")
+
+	filter, err := parseTimerFilter(r)
+	if err != nil {
+		logger.Logger.Warn("Invalid timer filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timers, err := database.GetRunningTimers(filter)
+	if err != nil {
+		logger.Logger.Error("Error getting running timers from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting running timers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	response := make([]models.TimerEvent, 0, len(timers))
+	for _, timer := range timers {
+		response = append(response, models.NewTimerEvent(models.TimerTick, timer, now))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// StreamTimers pushes timer events as Server-Sent Events, or over a WebSocket when the
+// request asks for an upgrade. The stream opens with a tick for every running timer.
+func StreamTimers(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("StreamTimers handler called")
+	defer logger.Logger.Info("StreamTimers handler finished")
+
+	filter, err := parseTimerFilter(r)
+	if err != nil {
+		logger.Logger.Warn("Invalid timer filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval := defaultTickInterval
+	if intervalString := r.URL.Query().Get("interval"); intervalString != "" {
+		seconds, err := strconv.Atoi(intervalString)
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > maxTickInterval {
+			logger.Logger.Warn("Invalid tick interval", zap.String("intervalString", intervalString), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Invalid interval: %v (1 to %d seconds)", intervalString, int(maxTickInterval.Seconds())),
+				http.StatusBadRequest)
+			return
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	// Subscribe before reading the running timers, so no change falls in between.
+	sub := events.Subscribe()
+	defer events.Unsubscribe(sub)
+
+	timers, err := database.GetRunningTimers(filter)
+	if err != nil {
+		logger.Logger.Error("Error getting running timers from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting running timers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var sink timerSink
+	ctx := r.Context()
+	if isWebSocketUpgrade(r) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			logger.Logger.Warn("WebSocket upgrade failed", zap.Error(err))
+			return
+		}
+		defer conn.Close(wsCloseNormal)
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			defer cancel()
+			conn.readLoop()
+		}()
+		sink = conn
+	} else {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			logger.Logger.Error("Streaming is not supported by the response writer")
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+		flusher.Flush()
+		sink = &sseSink{w: w, flusher: flusher}
+	}
+
+	err = streamTimers(ctx, sink, filter, timers, interval, sub)
+	if err != nil {
+		logger.Logger.Info("Timer stream closed", zap.Error(err))
+	}
+}
+
+// timerSink is the transport of a timer stream.
+type timerSink interface {
+	WriteText(data []byte) error
+	Ping() error
+}
+
+type sseSink struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	id      int
+}
+
+// WriteText writes one event. The event name is taken from the type of the message, so
+// EventSource clients can listen to timer.started and the rest separately.
+func (s *sseSink) WriteText(data []byte) error {
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	s.id++
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", s.id, event.Type, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// Ping writes a comment line, which keeps proxies from closing an idle stream.
+func (s *sseSink) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func streamTimers(ctx context.Context, sink timerSink, filter models.TimerFilter, timers []models.Timer,
+	interval time.Duration, sub *events.Subscription) error {
+	send := func(event models.TimerEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return sink.WriteText(data)
+	}
+
+	running := make(map[int]models.Timer, len(timers))
+	for _, timer := range timers {
+		running[timer.TaskID] = timer
+	}
+	tick := func() error {
+		if len(running) == 0 {
+			return sink.Ping()
+		}
+		ids := make([]int, 0, len(running))
+		for id := range running {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		now := time.Now()
+		for _, id := range ids {
+			if err := send(models.NewTimerEvent(models.TimerTick, running[id], now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := tick(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-sub.C:
+			if !filter.Match(event.Timer) {
+				continue
+			}
+			switch event.Type {
+			case models.TimerStarted:
+				running[event.TaskID] = event.Timer
+			case models.TimerStopped:
+				delete(running, event.TaskID)
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := tick(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseTimerFilter(r *http.Request) (models.TimerFilter, error) {
+	query := r.URL.Query()
+	filter := models.TimerFilter{Team: query.Get("team")}
+	if userIdString := query.Get("userId"); userIdString != "" {
+		userId, err := strconv.Atoi(userIdString)
+		if err != nil || userId < 1 {
+			return filter, fmt.Errorf("Invalid user ID: %v", userIdString)
+		}
+		filter.UserID = userId
+	}
+	return filter, nil
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/export"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// GetTimesheet returns the monthly timesheet of a user as a PDF document, or as a CSV
+// or XLSX report.
+func GetTimesheet(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetTimesheet handler called")
+	defer logger.Logger.Info("GetTimesheet handler finished")
+
+	userIdString := chi.URLParam(r, "id")
+	userId, err := strconv.Atoi(userIdString)
+	if err != nil || userId < 1 {
+		logger.Logger.Warn("Invalid user ID", zap.String("userIdString", userIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid user ID: %v", userIdString), http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseMonth(r.URL.Query().Get("month"))
+	if err != nil {
+		logger.Logger.Warn("Invalid month", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, err := timesheetFormat(r)
+	if err != nil {
+		logger.Logger.Warn("Invalid timesheet format", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := database.GetUser(userId)
+	if errors.Is(err, database.ErrUserNotFound) {
+		logger.Logger.Warn("User does not exist", zap.Int("userId", userId))
+		http.Error(w, fmt.Sprintf("User with id %d not exist", userId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting user", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format != formatPDF {
+		timesheet, err := loadTimesheet(user, month)
+		if err != nil {
+			logger.Logger.Error("Error building timesheet", zap.Int("userId", userId), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error building timesheet: %v", err), http.StatusInternalServerError)
+			return
+		}
+		days, summary := export.TimesheetTables(timesheet)
+		writeReport(w, r, format, timesheetName(user, month), days, summary)
+		return
+	}
+
+	var document bytes.Buffer
+	if err := writeTimesheet(&document, user, month); err != nil {
+		logger.Logger.Error("Error building timesheet", zap.Int("userId", userId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error building timesheet: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", timesheetFilename(user, month)))
+	w.Write(document.Bytes())
+	logger.Logger.Info("Timesheet sent", zap.Int("userId", userId), zap.String("month", month.Format("2006-01")))
+}
+
+// GetTeamTimesheets returns a ZIP archive with one PDF timesheet per user of a team
+// or of an explicit list of users, or the timesheets of all of them as one CSV or XLSX
+// report.
+func GetTeamTimesheets(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetTeamTimesheets handler called")
+	defer logger.Logger.Info("GetTeamTimesheets handler finished")
+
+	query := r.URL.Query()
+
+	month, err := parseMonth(query.Get("month"))
+	if err != nil {
+		logger.Logger.Warn("Invalid month", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, err := timesheetFormat(r)
+	if err != nil {
+		logger.Logger.Warn("Invalid timesheet format", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := models.UserFilter{Team: query.Get("team")}
+	for _, idsString := range query["ids"] {
+		for _, idString := range strings.Split(idsString, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idString))
+			if err != nil || id < 1 {
+				logger.Logger.Warn("Invalid user ID in ids filter", zap.String("idString", idString), zap.Error(err))
+				http.Error(w, fmt.Sprintf("Invalid user ID: %v", idString), http.StatusBadRequest)
+				return
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+	if filter.Team == "" && len(filter.IDs) == 0 {
+		logger.Logger.Warn("Team timesheets requested without team or ids")
+		http.Error(w, "Either team or ids must be specified", http.StatusBadRequest)
+		return
+	}
+
+	users, err := database.GetAllUsers(filter)
+	if err != nil {
+		logger.Logger.Error("Error getting users", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting users: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(users) == 0 {
+		logger.Logger.Warn("No users found for team timesheets", zap.String("team", filter.Team))
+		http.Error(w, "No users found", http.StatusNotFound)
+		return
+	}
+
+	name := "timesheets"
+	if filter.Team != "" {
+		name += "-" + filter.Team
+	}
+	name += "-" + month.Format("2006-01")
+
+	if format != formatPDF {
+		timesheets := make([]export.Timesheet, 0, len(users))
+		for _, user := range users {
+			timesheet, err := loadTimesheet(user, month)
+			if err != nil {
+				logger.Logger.Error("Error building timesheet", zap.Int("userId", user.ID), zap.Error(err))
+				http.Error(w, fmt.Sprintf("Error building timesheet for user %d: %v", user.ID, err), http.StatusInternalServerError)
+				return
+			}
+			timesheets = append(timesheets, timesheet)
+		}
+		days, summary := export.TimesheetTables(timesheets...)
+		writeReport(w, r, format, name, days, summary)
+		return
+	}
+
+	// The archive is built in memory so that a failure can still be reported with a proper status.
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, user := range users {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: timesheetFilename(user, month), Method: zip.Deflate, Modified: time.Now()})
+		if err == nil {
+			err = writeTimesheet(fw, user, month)
+		}
+		if err != nil {
+			logger.Logger.Error("Error building timesheet", zap.Int("userId", user.ID), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error building timesheet for user %d: %v", user.ID, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		logger.Logger.Error("Error building timesheet archive", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error building timesheet archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", name+".zip"))
+	w.Write(archive.Bytes())
+	logger.Logger.Info("Team timesheets sent", zap.Int("users", len(users)), zap.String("month", month.Format("2006-01")))
+}
+
+func writeTimesheet(w io.Writer, user models.User, month time.Time) error {
+	timesheet, err := loadTimesheet(user, month)
+	if err != nil {
+		return err
+	}
+	return export.WriteTimesheetPDF(w, timesheet)
+}
+
+func loadTimesheet(user models.User, month time.Time) (export.Timesheet, error) {
+	start, end := export.MonthPeriod(month)
+	tasks, err := database.GetTasksByPeriod(user.ID, start, end)
+	if err != nil {
+		return export.Timesheet{}, err
+	}
+	return export.NewTimesheet(user, month, tasks), nil
+}
+
+// timesheetFormat picks PDF, the default, or a CSV or XLSX report, asked for with the
+// format parameter or the Accept header as in reportFormat.
+func timesheetFormat(r *http.Request) (string, error) {
+	requested := strings.ToLower(r.URL.Query().Get("format"))
+	if requested == formatPDF {
+		return formatPDF, nil
+	}
+	format, err := reportFormat(r)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case format == formatCSV, format == formatXLSX:
+		return format, nil
+	case requested != "":
+		return "", fmt.Errorf("unsupported format: %s", requested)
+	}
+	return formatPDF, nil
+}
+
+// parseMonth parses a month in YYYY-MM format, defaulting to the current month.
+func parseMonth(value string) (time.Time, error) {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
+	}
+	month, err := time.ParseInLocation("2006-01", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid month: %s, expected YYYY-MM", value)
+	}
+	return month, nil
+}
+
+func timesheetFilename(user models.User, month time.Time) string {
+	return timesheetName(user, month) + ".pdf"
+}
+
+func timesheetName(user models.User, month time.Time) string {
+	return fmt.Sprintf("timesheet-%d-%s", user.ID, month.Format("2006-01"))
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// webhookCreatedResponse is returned once on creation; the secret cannot be read later.
+type webhookCreatedResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+type webhookDeliveryPage struct {
+	Items []models.WebhookDelivery `json:"items"`
+	Page  models.PageInfo          `json:"page"`
+}
+
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("CreateWebhook handler called")
+	defer logger.Logger.Info("CreateWebhook handler finished")
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := req.Validate(true); len(errs) > 0 {
+		logger.Logger.Warn("Invalid webhook", zap.Any("errors", errs))
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	webhook, secret, err := database.CreateWebhook(actorFromRequest(r), req)
+	if err != nil {
+		logger.Logger.Error("Error creating webhook", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error creating webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, webhookCreatedResponse{Webhook: webhook, Secret: secret})
+	logger.Logger.Info("Webhook created", zap.Int("webhookId", webhook.ID))
+}
+
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetWebhooks handler called")
+	defer logger.Logger.Info("GetWebhooks handler finished")
+
+	webhooks, err := database.GetWebhooks()
+	if err != nil {
+		logger.Logger.Error("Error getting webhooks from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhooks)
+}
+
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetWebhook handler called")
+	defer logger.Logger.Info("GetWebhook handler finished")
+
+	webhookId, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := database.GetWebhook(webhookId)
+	if errors.Is(err, database.ErrWebhookNotFound) {
+		logger.Logger.Warn("Webhook does not exist", zap.Int("webhookId", webhookId))
+		http.Error(w, fmt.Sprintf("Webhook with id %d not exist", webhookId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error getting webhook from database", zap.Int("webhookId", webhookId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("UpdateWebhook handler called")
+	defer logger.Logger.Info("UpdateWebhook handler finished")
+
+	webhookId, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Logger.Warn("Invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := req.Validate(false); len(errs) > 0 {
+		logger.Logger.Warn("Invalid webhook", zap.Any("errors", errs))
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	webhook, err := database.UpdateWebhook(actorFromRequest(r), webhookId, req)
+	if errors.Is(err, database.ErrWebhookNotFound) {
+		logger.Logger.Warn("Webhook does not exist", zap.Int("webhookId", webhookId))
+		http.Error(w, fmt.Sprintf("Webhook with id %d not exist", webhookId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error updating webhook", zap.Int("webhookId", webhookId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error updating webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, webhook)
+	logger.Logger.Info("Webhook updated", zap.Int("webhookId", webhookId))
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("DeleteWebhook handler called")
+	defer logger.Logger.Info("DeleteWebhook handler finished")
+
+	webhookId, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := database.DeleteWebhook(actorFromRequest(r), webhookId)
+	if errors.Is(err, database.ErrWebhookNotFound) {
+		logger.Logger.Warn("Webhook does not exist", zap.Int("webhookId", webhookId))
+		http.Error(w, fmt.Sprintf("Webhook with id %d not exist", webhookId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error deleting webhook", zap.Int("webhookId", webhookId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error deleting webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Logger.Info("Webhook deleted", zap.Int("webhookId", webhookId))
+}
+
+// GetWebhookDeliveries lists the delivery log of a webhook, newest first, optionally
+// filtered by status.
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("GetWebhookDeliveries handler called")
+	defer logger.Logger.Info("GetWebhookDeliveries handler finished")
+
+	webhookId, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.DeliveryFilter{WebhookID: webhookId, Status: query.Get("status")}
+	switch filter.Status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed:
+	default:
+		logger.Logger.Warn("Invalid delivery status", zap.String("status", filter.Status))
+		http.Error(w, fmt.Sprintf("Invalid status: %v", filter.Status), http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(query)
+	if err != nil {
+		logger.Logger.Warn("Invalid pagination parameters", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := database.GetWebhook(webhookId); err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			logger.Logger.Warn("Webhook does not exist", zap.Int("webhookId", webhookId))
+			http.Error(w, fmt.Sprintf("Webhook with id %d not exist", webhookId), http.StatusNotFound)
+			return
+		}
+		logger.Logger.Error("Error getting webhook from database", zap.Int("webhookId", webhookId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	deliveries, more, err := database.GetWebhookDeliveries(filter, page)
+	if err != nil {
+		logger.Logger.Error("Error getting webhook deliveries from database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting webhook deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := database.CountWebhookDeliveries(filter)
+	if err != nil {
+		logger.Logger.Error("Error counting webhook deliveries in database", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error getting webhook deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	deliveryCursor := func(delivery models.WebhookDelivery) models.Cursor {
+		return models.Cursor{ID: delivery.ID}
+	}
+	info := pageInfo(w, r, page, total, len(deliveries), more,
+		func() models.Cursor { return deliveryCursor(deliveries[0]) },
+		func() models.Cursor { return deliveryCursor(deliveries[len(deliveries)-1]) })
+
+	writeJSON(w, http.StatusOK, webhookDeliveryPage{Items: deliveries, Page: info})
+}
+
+// RetryWebhookDelivery queues a delivery for another attempt, including a delivery
+// that has already failed for good or succeeded.
+func RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	logger.Logger.Info("RetryWebhookDelivery handler called")
+	defer logger.Logger.Info("RetryWebhookDelivery handler finished")
+
+	webhookId, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryIdString := chi.URLParam(r, "deliveryId")
+	deliveryId, err := strconv.Atoi(deliveryIdString)
+	if err != nil || deliveryId < 1 {
+		logger.Logger.Warn("Invalid delivery ID", zap.String("deliveryIdString", deliveryIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid delivery ID: %v", deliveryIdString), http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := database.RetryWebhookDelivery(webhookId, deliveryId)
+	if errors.Is(err, database.ErrDeliveryNotFound) {
+		logger.Logger.Warn("Webhook delivery does not exist", zap.Int("webhookId", webhookId), zap.Int("deliveryId", deliveryId))
+		http.Error(w, fmt.Sprintf("Delivery with id %d not exist", deliveryId), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Logger.Error("Error retrying webhook delivery", zap.Int("deliveryId", deliveryId), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error retrying webhook delivery: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+	logger.Logger.Info("Webhook delivery queued for retry", zap.Int("deliveryId", deliveryId))
+}
+
+func webhookIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	webhookIdString := chi.URLParam(r, "id")
+	webhookId, err := strconv.Atoi(webhookIdString)
+	if err != nil || webhookId < 1 {
+		logger.Logger.Warn("Invalid webhook ID", zap.String("webhookIdString", webhookIdString), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid webhook ID: %v", webhookIdString), http.StatusBadRequest)
+		return 0, false
+	}
+	return webhookId, true
+}
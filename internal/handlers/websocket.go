@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal server side of RFC 6455, enough to push JSON messages to browsers: text
+// frames are sent unfragmented, and incoming frames are only read to answer pings and
+// to notice the client closing the connection. A client breaking the protocol gets a
+// close frame with the reason and is disconnected.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	// wsMaxMessageSize bounds frames read from clients, which have no reason to send
+	// anything large to a push-only endpoint.
+	wsMaxMessageSize = 4 << 10
+
+	// wsMaxControlSize is the largest payload of a control frame allowed by RFC 6455.
+	wsMaxControlSize = 125
+
+	// wsCloseTimeout bounds the wait for a client that does not read the close frame.
+	wsCloseTimeout = 5 * time.Second
+
+	wsCloseNormal        = 1000
+	wsCloseProtocolError = 1002
+	wsCloseTooLarge      = 1009
+)
+
+var errWebSocketClosed = errors.New("websocket connection closed")
+
+// allowedOrigins are the origins, besides the server's own, whose pages may open a
+// WebSocket. Unlike other requests, the handshake is not subject to CORS: browsers
+// send it with the visitor's cookies from any page, so without the check any site
+// could read the stream in the visitor's name.
+var allowedOrigins = map[string]bool{}
+
+// SetAllowedOrigins sets the origins, given as scheme and host, whose pages may open a
+// WebSocket besides the server's own.
+func SetAllowedOrigins(origins []string) {
+	allowedOrigins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowedOrigins[strings.ToLower(origin)] = true
+	}
+}
+
+// checkOrigin reports whether the page that opened the WebSocket may use it. Clients
+// other than browsers send no Origin and are let through.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return allowedOrigins[strings.ToLower(u.Scheme+"://"+u.Host)]
+}
+
+type wsConn struct {
+	conn      net.Conn
+	rw        *bufio.ReadWriter
+	mu        sync.Mutex // serializes frame writes
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newWSConn(conn net.Conn, rw *bufio.ReadWriter) *wsConn {
+	return &wsConn{conn: conn, rw: rw}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket completes the opening handshake and takes over the connection. On
+// failure it has already written the error response.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "WebSocket upgrade requires GET", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("websocket upgrade with method %s", r.Method)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "Origin is not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket upgrade from origin %s", r.Header.Get("Origin"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("invalid websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket is not supported by the server", http.StatusInternalServerError)
+		return nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %v", err)
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newWSConn(conn, rw), nil
+}
+
+func (c *wsConn) WriteText(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+func (c *wsConn) Ping() error {
+	return c.writeFrame(wsOpPing, nil)
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// Close sends a close frame with the status code and closes the connection. Only the
+// first call has an effect, so the reader and the handler may both close the
+// connection without sending a second close frame.
+func (c *wsConn) Close(code int) error {
+	c.closeOnce.Do(func() {
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		c.conn.SetWriteDeadline(time.Now().Add(wsCloseTimeout))
+		_ = c.writeFrame(wsOpClose, payload)
+		c.closeErr = c.conn.Close()
+	})
+	return c.closeErr
+}
+
+// readLoop consumes frames from the client until it closes the connection or an error
+// occurs, answering pings on the way. Data messages are discarded.
+func (c *wsConn) readLoop() error {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return err
+			}
+		case wsOpClose:
+			code := wsCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code)
+			return errWebSocketClosed
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	final := head[0]&0x80 != 0
+	reserved := head[0] & 0x70
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	switch opcode {
+	case wsOpContinuation, wsOpText, wsOpBinary:
+	case wsOpClose, wsOpPing, wsOpPong:
+		if !final || length > wsMaxControlSize {
+			c.Close(wsCloseProtocolError)
+			return 0, nil, errors.New("websocket control frame is fragmented or too long")
+		}
+	default:
+		c.Close(wsCloseProtocolError)
+		return 0, nil, fmt.Errorf("unknown websocket opcode %d", opcode)
+	}
+	if reserved != 0 {
+		c.Close(wsCloseProtocolError)
+		return 0, nil, errors.New("websocket frame has reserved bits set")
+	}
+	if !masked {
+		c.Close(wsCloseProtocolError)
+		return 0, nil, errors.New("websocket client frame is not masked")
+	}
+	if length > wsMaxMessageSize {
+		c.Close(wsCloseTooLarge)
+		return 0, nil, errors.New("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsPair connects a server-side wsConn to the client end of an in-memory pipe.
+func wsPair(t *testing.T) (*wsConn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	rw := bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))
+	return newWSConn(server, rw), client
+}
+
+// clientFrame builds a frame as a browser sends it: final and masked.
+func clientFrame(opcode byte, payload []byte) []byte {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads one unmasked frame sent by the server.
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if head[0]&0x80 == 0 || head[1]&0x80 != 0 {
+		t.Fatalf("frame header %x: want final and unmasked", head)
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("reading payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// readCloseCode reads a close frame sent by the server and returns its status code.
+func readCloseCode(t *testing.T, r io.Reader) int {
+	t.Helper()
+	opcode, payload := readServerFrame(t, r)
+	if opcode != wsOpClose || len(payload) != 2 {
+		t.Fatalf("got opcode %d with payload %x, want a close frame", opcode, payload)
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close(wsCloseNormal)
+		conn.WriteText([]byte(`{"type":"snapshot"}`))
+		conn.readLoop()
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The key and accept value are the example of RFC 6455, section 1.3.
+	request := "GET /timers/stream HTTP/1.1\r\nHost: " + server.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", response.StatusCode)
+	}
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", accept)
+	}
+
+	if opcode, payload := readServerFrame(t, reader); opcode != wsOpText || string(payload) != `{"type":"snapshot"}` {
+		t.Errorf("got opcode %d with %q, want the text message", opcode, payload)
+	}
+
+	conn.Write(clientFrame(wsOpPing, []byte("hi")))
+	if opcode, payload := readServerFrame(t, reader); opcode != wsOpPong || string(payload) != "hi" {
+		t.Errorf("got opcode %d with %q, want a pong echoing the ping", opcode, payload)
+	}
+
+	// The server echoes the client's close code once, though the handler closes too.
+	conn.Write(clientFrame(wsOpClose, []byte{0x03, 0xE9}))
+	if code := readCloseCode(t, reader); code != 1001 {
+		t.Errorf("close code = %d, want 1001", code)
+	}
+	if rest, err := io.ReadAll(reader); err != nil || len(rest) > 0 {
+		t.Errorf("after the close frame got %x, %v, want the connection closed", rest, err)
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/timers/stream", nil)
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		return r
+	}
+	tests := []struct {
+		name   string
+		change func(r *http.Request)
+		status int
+	}{
+		{"post", func(r *http.Request) { r.Method = http.MethodPost }, http.StatusMethodNotAllowed},
+		{"old version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"short key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, http.StatusBadRequest},
+		{"foreign origin", func(r *http.Request) { r.Header.Set("Origin", "https://evil.example") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := valid()
+		tt.change(r)
+		w := httptest.NewRecorder()
+		if _, err := upgradeWebSocket(w, r); err == nil || w.Code != tt.status {
+			t.Errorf("%s: got %d, %v, want %d", tt.name, w.Code, err, tt.status)
+		}
+	}
+}
+
+func TestWebSocketWriteFrameLengths(t *testing.T) {
+	for _, length := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		conn, client := wsPair(t)
+		payload := bytes.Repeat([]byte("x"), length)
+		go conn.WriteText(payload)
+		if opcode, got := readServerFrame(t, client); opcode != wsOpText || !bytes.Equal(got, payload) {
+			t.Errorf("length %d: got opcode %d with %d bytes", length, opcode, len(got))
+		}
+	}
+}
+
+func TestWebSocketCloseOnce(t *testing.T) {
+	conn, client := wsPair(t)
+	go func() {
+		conn.Close(wsCloseNormal)
+		conn.Close(wsCloseTooLarge)
+	}()
+	if code := readCloseCode(t, client); code != wsCloseNormal {
+		t.Errorf("close code = %d, want %d", code, wsCloseNormal)
+	}
+	if rest, err := io.ReadAll(client); err != nil || len(rest) > 0 {
+		t.Errorf("after the close frame got %x, %v, want the connection closed", rest, err)
+	}
+}
+
+func TestWebSocketRejectsBadFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		// Only the headers are sent: the server must give up before reading further.
+		{"fragmented ping", []byte{wsOpPing, 0x80}, wsCloseProtocolError},
+		{"long ping", []byte{0x80 | wsOpPing, 0x80 | 126, 0, 126}, wsCloseProtocolError},
+		{"long close", []byte{0x80 | wsOpClose, 0x80 | 126, 0, 200}, wsCloseProtocolError},
+		{"unmasked", []byte{0x80 | wsOpText, 5}, wsCloseProtocolError},
+		{"reserved bits", []byte{0xC0 | wsOpText, 0x80 | 5}, wsCloseProtocolError},
+		{"unknown opcode", []byte{0x83, 0x80}, wsCloseProtocolError},
+		{"too large", []byte{0x80 | wsOpText, 0x80 | 127, 0, 0, 0, 0, 0, 1, 0, 0}, wsCloseTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := wsPair(t)
+			done := make(chan error, 1)
+			go func() { done <- conn.readLoop() }()
+			go client.Write(tt.frame)
+
+			if code := readCloseCode(t, client); code != tt.code {
+				t.Errorf("close code = %d, want %d", code, tt.code)
+			}
+			if err := <-done; err == nil || strings.Contains(err.Error(), "closed") {
+				t.Errorf("readLoop returned %v, want the protocol error", err)
+			}
+		})
+	}
+}
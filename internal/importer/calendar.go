@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"time-tracker/internal/models"
+	"unicode/utf8"
+)
+
+// maxTitleLength matches the tasks.title column.
+const maxTitleLength = 200
+
+type CalendarRule struct {
+	rule    models.CalendarImportRule
+	pattern *regexp.Regexp
+}
+
+type CalendarImportOptions struct {
+	// SkipUnmatched skips events whose summary matches no rule instead of importing
+	// them under their summary.
+	SkipUnmatched bool
+	// Now is the end of running timers when checking for conflicts.
+	Now time.Time
+}
+
+// CompileRules validates the summary rules, which are applied in order.
+func CompileRules(rules []models.CalendarImportRule) ([]CalendarRule, error) {
+	compiled := make([]CalendarRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("rule %d: pattern is required", i+1)
+		}
+		pattern, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid pattern: %v", i+1, err)
+		}
+		compiled = append(compiled, CalendarRule{rule: rule, pattern: pattern})
+	}
+	return compiled, nil
+}
+
+// PlanCalendarImport turns calendar events into time entries of a user and marks the
+// ones that overlap existing entries or each other. Nothing is stored.
+func PlanCalendarImport(userId int, events []CalendarEvent, rules []CalendarRule, existing []models.Task, options CalendarImportOptions) []models.CalendarImportEntry {
+	entries := make([]models.CalendarImportEntry, 0, len(events))
+	var planned []models.Task
+
+	for _, event := range events {
+		entry := models.CalendarImportEntry{UID: event.UID, Summary: event.Summary}
+		if event.SkipReason != "" {
+			entry.Status = models.ImportStatusSkipped
+			entry.Reason = event.SkipReason
+			entries = append(entries, entry)
+			continue
+		}
+
+		title, description, matched := applyRules(rules, event)
+		if !matched && options.SkipUnmatched {
+			entry.Status = models.ImportStatusSkipped
+			entry.Reason = "no rule matches the summary"
+			entries = append(entries, entry)
+			continue
+		}
+
+		// Task times are stored as wall clock readings of the server's zone.
+		entry.Task = models.Task{
+			UserID:      userId,
+			Title:       truncate(title, maxTitleLength),
+			Description: description,
+			StartTime:   event.Start.In(time.Local),
+			EndTime:     event.End.In(time.Local),
+		}
+		entry.Status = models.ImportStatusNew
+
+		for _, task := range existing {
+			end := models.WallClock(task.EndTime)
+			if task.EndTime.IsZero() {
+				end = options.Now
+			}
+			if overlaps(entry.Task.StartTime, entry.Task.EndTime, models.WallClock(task.StartTime), end) {
+				entry.Conflicts = append(entry.Conflicts, task.TaskID)
+			}
+		}
+		if len(entry.Conflicts) > 0 {
+			entry.Status = models.ImportStatusConflict
+			entry.Reason = "overlaps existing time entries"
+		}
+		for _, task := range planned {
+			if overlaps(entry.Task.StartTime, entry.Task.EndTime, task.StartTime, task.EndTime) {
+				entry.Status = models.ImportStatusConflict
+				entry.Reason = "overlaps another event in the file"
+				break
+			}
+		}
+		planned = append(planned, entry.Task)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func applyRules(rules []CalendarRule, event CalendarEvent) (string, string, bool) {
+	for _, rule := range rules {
+		match := rule.pattern.FindStringSubmatchIndex(event.Summary)
+		if match == nil {
+			continue
+		}
+		title := event.Summary
+		if rule.rule.Title != "" {
+			title = string(rule.pattern.ExpandString(nil, rule.rule.Title, event.Summary, match))
+		}
+		description := event.Description
+		if rule.rule.Description != "" {
+			description = rule.rule.Description
+		}
+		return title, description, true
+	}
+	return event.Summary, event.Description, false
+}
+
+func overlaps(start, end, otherStart, otherEnd time.Time) bool {
+	return start.Before(otherEnd) && otherStart.Before(end)
+}
+
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
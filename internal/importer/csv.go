@@ -0,0 +1,589 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/enrichment"
+	"time-tracker/internal/export"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrInvalidFile marks problems with the file as a whole, as opposed to invalid rows,
+// which are reported row by row.
+var ErrInvalidFile = errors.New("invalid import file")
+
+const (
+	FieldUserID         = "user_id"
+	FieldPassportNumber = "passport_number"
+	FieldSurname        = "surname"
+	FieldName           = "name"
+	FieldPatronymic     = "patronymic"
+	FieldAddress        = "address"
+	FieldTeam           = "team"
+	FieldTitle          = "title"
+	FieldDescription    = "description"
+	FieldStartTime      = "start_time"
+	FieldEndTime        = "end_time"
+	FieldDuration       = "duration"
+)
+
+var userFields = []string{FieldPassportNumber, FieldSurname, FieldName, FieldPatronymic, FieldAddress, FieldTeam}
+
+var timeEntryFields = []string{FieldUserID, FieldPassportNumber, FieldTitle, FieldDescription, FieldStartTime, FieldEndTime, FieldDuration}
+
+// maxNameLength matches the users name and team columns.
+const maxNameLength = 100
+
+// timestampLayouts are tried in order for time entry timestamps. Layouts without a
+// zone are read in the server's zone.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+}
+
+type CSVImportOptions struct {
+	Dialect export.CSVOptions
+	// Columns maps field names to the CSV headers holding them. Fields that are not
+	// mapped are read from the column named after the field.
+	Columns        map[string]string
+	DryRun         bool
+	SkipEnrichment bool
+	Actor          models.Actor
+}
+
+// ParseColumnMapping reads a column mapping given as a JSON object, e.g.
+// {"surname": "Фамилия", "passport_number": "Паспорт"}.
+func ParseColumnMapping(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var columns map[string]string
+	if err := json.Unmarshal([]byte(value), &columns); err != nil {
+		return nil, fmt.Errorf("invalid column mapping: %v", err)
+	}
+	return columns, nil
+}
+
+type csvTable struct {
+	headers map[string]string
+	index   map[string]int
+	records [][]string
+}
+
+func (t *csvTable) has(field string) bool {
+	_, ok := t.index[field]
+	return ok
+}
+
+func (t *csvTable) get(record []string, field string) string {
+	i, ok := t.index[field]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// column names a field the way the file does, for row errors.
+func (t *csvTable) column(field string) string {
+	if header, ok := t.headers[field]; ok {
+		return header
+	}
+	return field
+}
+
+func readCSV(r io.Reader, options CSVImportOptions, fields []string) (*csvTable, error) {
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field] = true
+	}
+	for field := range options.Columns {
+		if !known[field] {
+			return nil, fmt.Errorf("%w: unknown field in column mapping: %s", ErrInvalidFile, field)
+		}
+	}
+
+	if options.Dialect.Encoding == export.EncodingWindows1251 {
+		r = charmap.Windows1251.NewDecoder().Reader(r)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = options.Dialect.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = ','
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	// Excel prepends a byte order mark to UTF-8 files.
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	table := &csvTable{headers: map[string]string{}, index: map[string]int{}}
+	for _, field := range fields {
+		name := field
+		if mapped, ok := options.Columns[field]; ok {
+			name = mapped
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if _, mapped := options.Columns[field]; mapped {
+				return nil, fmt.Errorf("%w: column %q mapped to %s is missing", ErrInvalidFile, name, field)
+			}
+			continue
+		}
+		table.index[field] = i
+		table.headers[field] = header[i]
+	}
+
+	table.records, err = reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return table, nil
+}
+
+func requireColumns(table *csvTable, fields ...string) error {
+	for _, field := range fields {
+		if !table.has(field) {
+			return fmt.Errorf("%w: required column %s is missing", ErrInvalidFile, field)
+		}
+	}
+	return nil
+}
+
+func countRows(report *models.BulkImportReport) {
+	for _, row := range report.Rows {
+		switch row.Status {
+		case models.ImportStatusNew:
+			report.New++
+		case models.ImportStatusExists:
+			report.Exists++
+		case models.ImportStatusInvalid:
+			report.Invalid++
+		case models.ImportStatusImported:
+			report.Imported++
+		}
+	}
+}
+
+// ImportUsersCSV creates users from a CSV file. Users are matched by passport number,
+// so running the same import again creates nothing. Unless enrichment is skipped,
+// missing personal data is requested from the external API; values from the file win.
+// Invalid rows are reported and skipped, the valid ones are stored in one transaction.
+func ImportUsersCSV(r io.Reader, options CSVImportOptions) (models.BulkImportReport, error) {
+	report := models.BulkImportReport{DryRun: options.DryRun}
+
+	table, err := readCSV(r, options, userFields)
+	if err != nil {
+		return report, err
+	}
+	if err := requireColumns(table, FieldPassportNumber); err != nil {
+		return report, err
+	}
+	if options.SkipEnrichment {
+		if err := requireColumns(table, FieldSurname, FieldName); err != nil {
+			return report, err
+		}
+	}
+
+	report.Total = len(table.records)
+	report.Rows = make([]models.BulkImportRow, len(table.records))
+	users := make([]models.User, len(table.records))
+	seen := map[string]int{}
+	var passports []string
+
+	for i, record := range table.records {
+		row := &report.Rows[i]
+		row.Row = i + 2
+		user := models.User{
+			Surname:    table.get(record, FieldSurname),
+			Name:       table.get(record, FieldName),
+			Patronymic: table.get(record, FieldPatronymic),
+			Address:    table.get(record, FieldAddress),
+			Team:       table.get(record, FieldTeam),
+		}
+
+		passport, fieldErr := validation.NormalizePassport(table.column(FieldPassportNumber), table.get(record, FieldPassportNumber))
+		if fieldErr != nil {
+			row.Errors = append(row.Errors, *fieldErr)
+		} else if first, ok := seen[passport]; ok {
+			row.Errors = append(row.Errors, validation.FieldError{
+				Field:   table.column(FieldPassportNumber),
+				Message: fmt.Sprintf("duplicates row %d", first),
+			})
+		} else {
+			seen[passport] = row.Row
+			passports = append(passports, passport)
+		}
+		user.PassportNumber = passport
+
+		row.Errors = append(row.Errors, validateUserFields(table, user, options.SkipEnrichment)...)
+		if len(row.Errors) > 0 {
+			row.Status = models.ImportStatusInvalid
+			continue
+		}
+		row.Status = models.ImportStatusNew
+		users[i] = user
+	}
+
+	existing, err := database.GetUserIDsByPassports(passports)
+	if err != nil {
+		return report, err
+	}
+
+	var pending []int
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status != models.ImportStatusNew {
+			continue
+		}
+		if id, ok := existing[users[i].PassportNumber]; ok {
+			row.Status = models.ImportStatusExists
+			row.ID = id
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	// The external API is not called on dry runs: the preview must be cheap and
+	// must not send personal data anywhere.
+	if !options.SkipEnrichment && !options.DryRun {
+		enriched := pending[:0]
+		for _, i := range pending {
+			if err := enrichUser(&users[i]); err != nil {
+				report.Rows[i].Status = models.ImportStatusInvalid
+				report.Rows[i].Errors = validation.Errors{{Field: table.column(FieldPassportNumber), Message: err.Error()}}
+				continue
+			}
+			if errs := validateUserFields(table, users[i], true); len(errs) > 0 {
+				report.Rows[i].Status = models.ImportStatusInvalid
+				report.Rows[i].Errors = errs
+				continue
+			}
+			enriched = append(enriched, i)
+		}
+		pending = enriched
+	}
+
+	if !options.DryRun && len(pending) > 0 {
+		toImport := make([]models.User, len(pending))
+		for n, i := range pending {
+			toImport[n] = users[i]
+		}
+		ids, err := database.ImportUsers(options.Actor, toImport)
+		if err != nil {
+			return report, err
+		}
+		for n, i := range pending {
+			report.Rows[i].Status = models.ImportStatusImported
+			report.Rows[i].ID = ids[n]
+		}
+	}
+
+	countRows(&report)
+	return report, nil
+}
+
+// enrichUser fills the fields the file left empty with data from the external API.
+func enrichUser(user *models.User) error {
+	apiUser, err := enrichment.Lookup(user.PassportNumber)
+	if err != nil {
+		return err
+	}
+	if user.Surname == "" {
+		user.Surname = apiUser.Surname
+	}
+	if user.Name == "" {
+		user.Name = apiUser.Name
+	}
+	if user.Patronymic == "" {
+		user.Patronymic = apiUser.Patronymic
+	}
+	if user.Address == "" {
+		user.Address = apiUser.Address
+	}
+	return nil
+}
+
+func validateUserFields(table *csvTable, user models.User, requireNames bool) validation.Errors {
+	var errs validation.Errors
+	if requireNames {
+		if user.Surname == "" {
+			errs = append(errs, validation.FieldError{Field: table.column(FieldSurname), Message: "surname is required"})
+		}
+		if user.Name == "" {
+			errs = append(errs, validation.FieldError{Field: table.column(FieldName), Message: "name is required"})
+		}
+	}
+	fields := []struct {
+		field string
+		value string
+	}{
+		{FieldSurname, user.Surname},
+		{FieldName, user.Name},
+		{FieldPatronymic, user.Patronymic},
+		{FieldTeam, user.Team},
+	}
+	for _, f := range fields {
+		if utf8.RuneCountInString(f.value) > maxNameLength {
+			errs = append(errs, validation.FieldError{
+				Field:   table.column(f.field),
+				Message: fmt.Sprintf("must be at most %d characters", maxNameLength),
+			})
+		}
+	}
+	return errs
+}
+
+// ImportTimeEntriesCSV creates completed time entries from a CSV file. Each row names
+// its user by ID or passport number. An entry is matched by its user and start time, so
+// running the same import again creates nothing. Invalid rows are reported and
+// skipped, the valid ones are stored in one transaction.
+func ImportTimeEntriesCSV(r io.Reader, options CSVImportOptions) (models.BulkImportReport, error) {
+	report := models.BulkImportReport{DryRun: options.DryRun}
+
+	table, err := readCSV(r, options, timeEntryFields)
+	if err != nil {
+		return report, err
+	}
+	if err := requireColumns(table, FieldStartTime); err != nil {
+		return report, err
+	}
+	if !table.has(FieldUserID) && !table.has(FieldPassportNumber) {
+		return report, fmt.Errorf("%w: either %s or %s column is required", ErrInvalidFile, FieldUserID, FieldPassportNumber)
+	}
+	if !table.has(FieldEndTime) && !table.has(FieldDuration) {
+		return report, fmt.Errorf("%w: either %s or %s column is required", ErrInvalidFile, FieldEndTime, FieldDuration)
+	}
+
+	report.Total = len(table.records)
+	report.Rows = make([]models.BulkImportRow, len(table.records))
+	tasks := make([]models.Task, len(table.records))
+	passports := make([]string, len(table.records))
+	var passportList []string
+	var userIDs []int
+
+	for i, record := range table.records {
+		row := &report.Rows[i]
+		row.Row = i + 2
+		task := models.Task{
+			Title:       truncate(table.get(record, FieldTitle), maxTitleLength),
+			Description: table.get(record, FieldDescription),
+		}
+
+		if idString := table.get(record, FieldUserID); idString != "" {
+			id, err := strconv.Atoi(idString)
+			if err != nil || id < 1 {
+				row.Errors = append(row.Errors, validation.FieldError{Field: table.column(FieldUserID), Message: "must be a positive integer"})
+			}
+			task.UserID = id
+			userIDs = append(userIDs, id)
+		} else {
+			passport, fieldErr := validation.NormalizePassport(table.column(FieldPassportNumber), table.get(record, FieldPassportNumber))
+			if fieldErr != nil {
+				row.Errors = append(row.Errors, *fieldErr)
+			}
+			passports[i] = passport
+			passportList = append(passportList, passport)
+		}
+
+		var fieldErr *validation.FieldError
+		task.StartTime, task.EndTime, fieldErr = parseEntryPeriod(table, record)
+		if fieldErr != nil {
+			row.Errors = append(row.Errors, *fieldErr)
+		}
+
+		if len(row.Errors) > 0 {
+			row.Status = models.ImportStatusInvalid
+			continue
+		}
+		row.Status = models.ImportStatusNew
+		tasks[i] = task
+	}
+
+	byPassport, err := database.GetUserIDsByPassports(passportList)
+	if err != nil {
+		return report, err
+	}
+	existingUsers, err := database.GetExistingUserIDs(userIDs)
+	if err != nil {
+		return report, err
+	}
+
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status != models.ImportStatusNew {
+			continue
+		}
+		if passports[i] != "" {
+			id, ok := byPassport[passports[i]]
+			if !ok {
+				row.Status = models.ImportStatusInvalid
+				row.Errors = validation.Errors{{Field: table.column(FieldPassportNumber), Message: "no user with this passport number"}}
+				continue
+			}
+			tasks[i].UserID = id
+		} else if !existingUsers[tasks[i].UserID] {
+			row.Status = models.ImportStatusInvalid
+			row.Errors = validation.Errors{{Field: table.column(FieldUserID), Message: "no user with this ID"}}
+		}
+	}
+
+	if err := storeTasks(&report, tasks, table.column(FieldStartTime), options.DryRun, options.Actor); err != nil {
+		return report, err
+	}
+	countRows(&report)
+	return report, nil
+}
+
+// storeTasks imports the entries of the rows that are still new, in one transaction.
+// An entry is matched by its user and start time: entries already stored are reported
+// as existing, repeated ones within the file as invalid. Nothing is written on dry runs.
+func storeTasks(report *models.BulkImportReport, tasks []models.Task, startField string, dryRun bool, actor models.Actor) error {
+	users := map[int]bool{}
+	var userIDs []int
+	for i, row := range report.Rows {
+		if row.Status == models.ImportStatusNew && !users[tasks[i].UserID] {
+			users[tasks[i].UserID] = true
+			userIDs = append(userIDs, tasks[i].UserID)
+		}
+	}
+	starts, err := database.GetTaskStarts(userIDs)
+	if err != nil {
+		return err
+	}
+
+	var pending []int
+	seen := map[int]map[int64]int{}
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status != models.ImportStatusNew {
+			continue
+		}
+		userID, start := tasks[i].UserID, tasks[i].StartTime.Unix()
+		if id, ok := starts[userID][start]; ok {
+			row.Status = models.ImportStatusExists
+			row.ID = id
+			continue
+		}
+		if first, ok := seen[userID][start]; ok {
+			row.Status = models.ImportStatusInvalid
+			row.Errors = validation.Errors{{Field: startField, Message: fmt.Sprintf("duplicates row %d", first)}}
+			continue
+		}
+		if seen[userID] == nil {
+			seen[userID] = map[int64]int{}
+		}
+		seen[userID][start] = row.Row
+		pending = append(pending, i)
+	}
+
+	if dryRun || len(pending) == 0 {
+		return nil
+	}
+	toImport := make([]models.Task, len(pending))
+	for n, i := range pending {
+		toImport[n] = tasks[i]
+	}
+	ids, err := database.ImportTasks(actor, toImport)
+	if err != nil {
+		return err
+	}
+	for n, i := range pending {
+		report.Rows[i].Status = models.ImportStatusImported
+		report.Rows[i].ID = ids[n]
+	}
+	return nil
+}
+
+func parseEntryPeriod(table *csvTable, record []string) (time.Time, time.Time, *validation.FieldError) {
+	start, err := parseTimestamp(table.get(record, FieldStartTime))
+	if err != nil {
+		return start, start, &validation.FieldError{Field: table.column(FieldStartTime), Message: err.Error()}
+	}
+
+	if endString := table.get(record, FieldEndTime); endString != "" {
+		end, err := parseTimestamp(endString)
+		if err != nil {
+			return start, end, &validation.FieldError{Field: table.column(FieldEndTime), Message: err.Error()}
+		}
+		if !end.After(start) {
+			return start, end, &validation.FieldError{Field: table.column(FieldEndTime), Message: "must be after the start time"}
+		}
+		return start, end, nil
+	}
+
+	duration, err := parseDuration(table.get(record, FieldDuration))
+	if err != nil {
+		return start, start, &validation.FieldError{Field: table.column(FieldDuration), Message: err.Error()}
+	}
+	return start, start.Add(duration), nil
+}
+
+// parseTimestamp reads a timestamp in one of timestampLayouts and returns it in the
+// server's zone, in which task times are stored.
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.In(time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %s, expected e.g. 2024-07-01 09:30 or RFC 3339", value)
+}
+
+// parseDuration reads a positive duration as H:MM, H:MM:SS or a Go duration like 1h30m.
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, errors.New("either end time or duration is required")
+	}
+
+	var duration time.Duration
+	if parts := strings.Split(value, ":"); len(parts) == 2 || len(parts) == 3 {
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration: %s", value)
+			}
+			duration += time.Duration(n) * units[i]
+		}
+	} else {
+		var err error
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+	}
+	if duration <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+	return duration, nil
+}
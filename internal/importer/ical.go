@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarEvent is a VEVENT read from an iCalendar file. Events that cannot become
+// time entries carry a SkipReason instead of being dropped, so the preview lists them.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	SkipReason  string
+}
+
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseCalendar reads the VEVENTs of an iCalendar (RFC 5545) document.
+func ParseCalendar(r io.Reader) ([]CalendarEvent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []CalendarEvent
+	var event *CalendarEvent
+	var duration time.Duration
+	// Nested components (VALARM) have their own DTSTART and DESCRIPTION properties.
+	nested := 0
+	seenCalendar := false
+
+	for number, line := range lines {
+		property, err := parseICalProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number+1, err)
+		}
+
+		switch {
+		case property.name == "BEGIN" && property.value == "VCALENDAR":
+			seenCalendar = true
+			continue
+		case property.name == "BEGIN" && property.value == "VEVENT":
+			event = &CalendarEvent{}
+			duration = 0
+			nested = 0
+			continue
+		case property.name == "END" && property.value == "VEVENT":
+			if event != nil {
+				finishCalendarEvent(event, duration)
+				events = append(events, *event)
+			}
+			event = nil
+			continue
+		case event == nil:
+			continue
+		case property.name == "BEGIN":
+			nested++
+			continue
+		case property.name == "END":
+			nested--
+			continue
+		case nested > 0:
+			continue
+		}
+
+		switch property.name {
+		case "UID":
+			event.UID = property.value
+		case "SUMMARY":
+			event.Summary = unescapeICalText(property.value)
+		case "DESCRIPTION":
+			event.Description = unescapeICalText(property.value)
+		case "DTSTART", "DTEND":
+			t, allDay, err := parseICalTime(property)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s: %v", number+1, property.name, err)
+			}
+			if allDay {
+				event.SkipReason = "all-day events are not time entries"
+			}
+			if property.name == "DTSTART" {
+				event.Start = t
+			} else {
+				event.End = t
+			}
+		case "DURATION":
+			duration, err = parseICalDuration(property.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid DURATION: %v", number+1, err)
+			}
+		case "RRULE":
+			event.SkipReason = "recurring events are not supported"
+		case "STATUS":
+			if property.value == "CANCELLED" {
+				event.SkipReason = "the event is cancelled"
+			}
+		}
+	}
+
+	if !seenCalendar {
+		return nil, errors.New("not an iCalendar file: VCALENDAR is missing")
+	}
+	return events, nil
+}
+
+func finishCalendarEvent(event *CalendarEvent, duration time.Duration) {
+	if event.End.IsZero() && duration > 0 {
+		event.End = event.Start.Add(duration)
+	}
+	if event.SkipReason != "" {
+		return
+	}
+	switch {
+	case event.Start.IsZero():
+		event.SkipReason = "the event has no start time"
+	case event.End.IsZero():
+		event.SkipReason = "the event has no end time or duration"
+	case !event.End.After(event.Start):
+		event.SkipReason = "the event ends before it starts"
+	}
+}
+
+// unfoldICalLines joins folded content lines: a line starting with a space or a tab
+// continues the previous one.
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	return lines, nil
+}
+
+func parseICalProperty(line string) (icalProperty, error) {
+	// The value starts at the first colon outside of a quoted parameter value.
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icalProperty{}, fmt.Errorf("malformed content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	property := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		property.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return property, nil
+}
+
+// parseICalTime parses DATE-TIME values in UTC, with a TZID or floating (in the
+// server's zone), and DATE values, which are reported as all-day.
+func parseICalTime(property icalProperty) (time.Time, bool, error) {
+	value := property.value
+	if property.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	location := time.Local
+	if tzid := property.params["TZID"]; tzid != "" {
+		// Unknown zone names (e.g. Windows ones) fall back to the server's zone.
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+// parseICalDuration parses RFC 5545 durations such as PT1H30M, P1D or P1W.
+func parseICalDuration(value string) (time.Duration, error) {
+	rest := strings.TrimPrefix(value, "+")
+	if strings.HasPrefix(rest, "-") {
+		return 0, errors.New("negative durations are not supported")
+	}
+	if !strings.HasPrefix(rest, "P") {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	rest = rest[1:]
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var duration time.Duration
+	number := ""
+	inTime := false
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == 'T':
+			inTime = true
+		case c >= '0' && c <= '9':
+			number += string(c)
+		default:
+			unit, ok := units[c]
+			if !ok || number == "" || (c == 'M' && !inTime) {
+				return 0, fmt.Errorf("malformed duration %q", value)
+			}
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("malformed duration %q", value)
+			}
+			duration += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	return duration, nil
+}
+
+func unescapeICalText(s string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+)
+
+type TrackerImportOptions struct {
+	Parse TrackerParseOptions
+	// Users maps user names or emails of the tracker (case-insensitive) to our user IDs.
+	// Users that are not mapped are matched by full name.
+	Users  map[string]int
+	DryRun bool
+	Actor  models.Actor
+}
+
+// ParseUserMapping reads a user mapping given as a JSON object, e.g.
+// {"jane@example.com": 3, "Иван Петров": 5}.
+func ParseUserMapping(value string) (map[string]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var users map[string]int
+	if err := json.Unmarshal([]byte(value), &users); err != nil {
+		return nil, fmt.Errorf("invalid user mapping: %v", err)
+	}
+	return users, nil
+}
+
+// ImportTrackerExport imports the time entries of a Toggl Track, Clockify or Harvest
+// export. Projects and tasks become the titles of our entries ("Project / Task"), and
+// times are converted to the server's zone in which entries are stored. Like the CSV
+// import, entries are matched by user and start time, so re-running it is safe.
+func ImportTrackerExport(source string, r io.Reader, options TrackerImportOptions) (models.BulkImportReport, error) {
+	report := models.BulkImportReport{DryRun: options.DryRun}
+
+	entries, err := ParseTrackerExport(source, r, options.Parse)
+	if err != nil {
+		return report, err
+	}
+
+	mapping := make(map[string]int, len(options.Users))
+	var mappedIDs []int
+	for key, id := range options.Users {
+		mapping[strings.ToLower(strings.TrimSpace(key))] = id
+		mappedIDs = append(mappedIDs, id)
+	}
+	existingUsers, err := database.GetExistingUserIDs(mappedIDs)
+	if err != nil {
+		return report, err
+	}
+	for key, id := range mapping {
+		if !existingUsers[id] {
+			return report, fmt.Errorf("%w: user mapping %q refers to a missing user %d", ErrInvalidFile, key, id)
+		}
+	}
+
+	report.Total = len(entries)
+	report.Rows = make([]models.BulkImportRow, len(entries))
+	tasks := make([]models.Task, len(entries))
+	matched := map[string]userMatch{}
+
+	for i, entry := range entries {
+		row := &report.Rows[i]
+		row.Row = entry.Row
+		if len(entry.Errors) > 0 {
+			row.Status = models.ImportStatusInvalid
+			row.Errors = entry.Errors
+			continue
+		}
+
+		user, err := resolveTrackerUser(entry, mapping, matched)
+		if err != nil {
+			return report, err
+		}
+		if user.id == 0 {
+			row.Status = models.ImportStatusInvalid
+			row.Errors = validation.Errors{{Field: "user", Message: user.problem}}
+			continue
+		}
+
+		row.Status = models.ImportStatusNew
+		tasks[i] = models.Task{
+			UserID:      user.id,
+			Title:       truncate(trackerTitle(entry), maxTitleLength),
+			Description: entry.Description,
+			StartTime:   entry.Start.In(time.Local),
+			EndTime:     entry.End.In(time.Local),
+		}
+	}
+
+	if err := storeTasks(&report, tasks, "start", options.DryRun, options.Actor); err != nil {
+		return report, err
+	}
+	countRows(&report)
+	return report, nil
+}
+
+type userMatch struct {
+	id      int
+	problem string
+}
+
+// resolveTrackerUser finds our user for an entry: by the mapping (email first, then
+// name) or by full name in either order, "Name Surname" or "Surname Name". Without a
+// single match the ID is 0 and the problem says why. Matches are cached in matched.
+func resolveTrackerUser(entry TrackerEntry, mapping map[string]int, matched map[string]userMatch) (userMatch, error) {
+	if id, ok := mapping[strings.ToLower(entry.Email)]; ok && entry.Email != "" {
+		return userMatch{id: id}, nil
+	}
+	name := strings.ToLower(entry.User)
+	if id, ok := mapping[name]; ok && name != "" {
+		return userMatch{id: id}, nil
+	}
+	key := firstNonEmpty(name, strings.ToLower(entry.Email))
+	if match, ok := matched[key]; ok {
+		return match, nil
+	}
+
+	match := userMatch{problem: fmt.Sprintf("no user named %q, map it with the users option", firstNonEmpty(entry.User, entry.Email))}
+	words := strings.Fields(entry.User)
+	if len(words) >= 2 {
+		found := map[int]bool{}
+		first, rest := words[0], strings.Join(words[1:], " ")
+		last, others := words[len(words)-1], strings.Join(words[:len(words)-1], " ")
+		for _, filter := range []models.UserFilter{
+			{Name: first, Surname: rest},
+			{Surname: first, Name: rest},
+			{Name: others, Surname: last},
+		} {
+			users, err := database.GetAllUsers(filter)
+			if err != nil {
+				return match, err
+			}
+			for _, user := range users {
+				found[user.ID] = true
+			}
+		}
+		switch {
+		case len(found) == 1:
+			for id := range found {
+				match = userMatch{id: id}
+			}
+		case len(found) > 1:
+			match.problem = fmt.Sprintf("several users are named %q, map it with the users option", entry.User)
+		}
+	}
+	matched[key] = match
+	return match, nil
+}
+
+func trackerTitle(entry TrackerEntry) string {
+	var parts []string
+	for _, part := range []string{entry.Project, entry.Task} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return entry.Description
+	}
+	return strings.Join(parts, " / ")
+}
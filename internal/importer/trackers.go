@@ -0,0 +1,515 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"time-tracker/internal/validation"
+)
+
+// ErrUnsupportedSource is returned for sources other than Toggl, Clockify and Harvest.
+var ErrUnsupportedSource = errors.New("unsupported source")
+
+// Sources of time tracker exports.
+const (
+	SourceToggl    = "toggl"
+	SourceClockify = "clockify"
+	SourceHarvest  = "harvest"
+)
+
+// TrackerEntry is a time entry read from the export of another tracker. Rows that cannot
+// be read keep their Errors, so the report can point at them.
+type TrackerEntry struct {
+	Row         int
+	User        string
+	Email       string
+	Project     string
+	Task        string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Errors      validation.Errors
+
+	// dateOnly marks entries that have a date and a length but no start time.
+	dateOnly bool
+}
+
+// TrackerParseOptions controls how times without a zone are read.
+type TrackerParseOptions struct {
+	// Location is the zone of exports that carry local times only (Toggl, Clockify and
+	// Harvest CSV reports use the zone of the exporting user's profile).
+	Location *time.Location
+	// DayStart is when the first entry of a day starts for Harvest entries that have
+	// hours but no start time. Later entries of the same user and day follow it.
+	DayStart time.Duration
+}
+
+// ParseTrackerOptions reads the zone of exports without offsets (the server's zone by
+// default) and the start of the working day for Harvest entries ("09:00" by default).
+func ParseTrackerOptions(timezone, dayStart string) (TrackerParseOptions, error) {
+	options := TrackerParseOptions{Location: time.Local, DayStart: 9 * time.Hour}
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return options, fmt.Errorf("invalid timezone: %s", timezone)
+		}
+		options.Location = location
+	}
+	if dayStart != "" {
+		t, err := time.Parse("15:04", dayStart)
+		if err != nil {
+			return options, fmt.Errorf("invalid day start: %s, expected HH:MM", dayStart)
+		}
+		options.DayStart = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return options, nil
+}
+
+var trackerDateLayouts = []string{"2006-01-02", "01/02/2006", "02.01.2006", "2006/01/02"}
+
+var trackerClockLayouts = []string{"15:04:05", "15:04", "03:04:05 PM", "3:04:05 PM", "03:04 PM", "3:04 PM", "3:04pm", "3:04PM"}
+
+// ParseTrackerExport reads a Toggl Track, Clockify or Harvest export. Both the CSV
+// detailed reports and the JSON exports of their APIs are accepted; the format is
+// detected from the content.
+func ParseTrackerExport(source string, r io.Reader, options TrackerParseOptions) ([]TrackerEntry, error) {
+	if options.Location == nil {
+		options.Location = time.Local
+	}
+
+	br := bufio.NewReader(r)
+	// Skip a byte order mark and leading whitespace to see whether this is JSON.
+	var first byte
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+			br.Discard(3)
+			continue
+		}
+		first = b[0]
+		break
+	}
+	isJSON := first == '[' || first == '{'
+
+	var entries []TrackerEntry
+	var err error
+	switch {
+	case source == SourceToggl && isJSON:
+		entries, err = parseTogglJSON(br)
+	case source == SourceToggl:
+		entries, err = parseTrackerCSV(br, options, togglCSVRow)
+	case source == SourceClockify && isJSON:
+		entries, err = parseClockifyJSON(br)
+	case source == SourceClockify:
+		entries, err = parseTrackerCSV(br, options, clockifyCSVRow)
+	case source == SourceHarvest && isJSON:
+		entries, err = parseHarvestJSON(br, options)
+	case source == SourceHarvest:
+		entries, err = parseTrackerCSV(br, options, harvestCSVRow)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if source == SourceHarvest {
+		placeHarvestEntries(entries, options)
+	}
+	for i := range entries {
+		checkTrackerEntry(&entries[i])
+	}
+	return entries, nil
+}
+
+func checkTrackerEntry(entry *TrackerEntry) {
+	if len(entry.Errors) > 0 {
+		return
+	}
+	if entry.User == "" && entry.Email == "" {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: "user", Message: "the entry has no user"})
+	}
+	if !entry.End.After(entry.Start) {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: "end", Message: "the entry must end after it starts"})
+	}
+}
+
+// csvRow gives access to a CSV record by case-insensitive header names.
+type csvRow struct {
+	index  map[string]int
+	record []string
+}
+
+func (r csvRow) get(names ...string) string {
+	for _, name := range names {
+		if i, ok := r.index[strings.ToLower(name)]; ok && i < len(r.record) {
+			return strings.TrimSpace(r.record[i])
+		}
+	}
+	return ""
+}
+
+func parseTrackerCSV(r io.Reader, options TrackerParseOptions, parseRow func(csvRow, TrackerParseOptions, *TrackerEntry)) ([]TrackerEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	entries := make([]TrackerEntry, len(records))
+	for i, record := range records {
+		entries[i].Row = i + 2
+		parseRow(csvRow{index: index, record: record}, options, &entries[i])
+	}
+	return entries, nil
+}
+
+// togglCSVRow reads a row of the Toggl Track detailed report.
+func togglCSVRow(row csvRow, options TrackerParseOptions, entry *TrackerEntry) {
+	entry.User = row.get("User")
+	entry.Email = row.get("Email")
+	entry.Project = row.get("Project")
+	entry.Task = row.get("Task")
+	entry.Description = row.get("Description")
+	readCSVPeriod(row, options, entry, "Start date", "Start time", "End date", "End time", "Duration")
+}
+
+// clockifyCSVRow reads a row of the Clockify detailed report.
+func clockifyCSVRow(row csvRow, options TrackerParseOptions, entry *TrackerEntry) {
+	entry.User = row.get("User")
+	entry.Email = row.get("Email")
+	entry.Project = row.get("Project")
+	entry.Task = row.get("Task")
+	entry.Description = row.get("Description")
+	readCSVPeriod(row, options, entry, "Start Date", "Start Time", "End Date", "End Time", "Duration (h)")
+}
+
+// harvestCSVRow reads a row of the Harvest detailed time report, which has a date and
+// hours but no start time; placeHarvestEntries lays the entries out over the day.
+func harvestCSVRow(row csvRow, options TrackerParseOptions, entry *TrackerEntry) {
+	entry.User = strings.TrimSpace(row.get("First Name") + " " + row.get("Last Name"))
+	entry.Project = row.get("Project")
+	entry.Task = row.get("Task")
+	entry.Description = row.get("Notes")
+
+	date, err := parseTrackerDate(row.get("Date"), options.Location)
+	if err != nil {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: "Date", Message: err.Error()})
+		return
+	}
+	hours, err := strconv.ParseFloat(strings.ReplaceAll(row.get("Hours"), ",", "."), 64)
+	if err != nil || hours <= 0 {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: "Hours", Message: "must be a positive number"})
+		return
+	}
+	entry.Start = date
+	entry.End = date.Add(hoursDuration(hours))
+	entry.dateOnly = true
+}
+
+func readCSVPeriod(row csvRow, options TrackerParseOptions, entry *TrackerEntry, startDate, startTime, endDate, endTime, duration string) {
+	start, err := parseTrackerDateTime(row.get(startDate), row.get(startTime), options.Location)
+	if err != nil {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: startTime, Message: err.Error()})
+		return
+	}
+	entry.Start = start
+
+	if row.get(endTime) != "" {
+		date := row.get(endDate)
+		if date == "" {
+			date = row.get(startDate)
+		}
+		end, err := parseTrackerDateTime(date, row.get(endTime), options.Location)
+		if err != nil {
+			entry.Errors = append(entry.Errors, validation.FieldError{Field: endTime, Message: err.Error()})
+			return
+		}
+		entry.End = end
+		return
+	}
+
+	length, err := parseDuration(row.get(duration))
+	if err != nil {
+		entry.Errors = append(entry.Errors, validation.FieldError{Field: duration, Message: err.Error()})
+		return
+	}
+	entry.End = start.Add(length)
+}
+
+func parseTrackerDate(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range trackerDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported date format: %q", value)
+}
+
+func parseTrackerDateTime(date, clock string, location *time.Location) (time.Time, error) {
+	day, err := parseTrackerDate(date, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range trackerClockLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(clock)); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %q", clock)
+}
+
+func hoursDuration(hours float64) time.Duration {
+	return time.Duration(math.Round(hours*float64(time.Hour)/float64(time.Second))) * time.Second
+}
+
+// parseJSONEntries decodes either a plain array of entries or an object that holds the
+// array under one of the given keys.
+func parseJSONEntries(r io.Reader, keys ...string) ([]json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", ErrInvalidFile, err)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return items, nil
+	}
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, fmt.Errorf("%w: expected an array of time entries", ErrInvalidFile)
+	}
+	for _, key := range keys {
+		if list, ok := wrapper[key]; ok {
+			if err := json.Unmarshal(list, &items); err != nil {
+				return nil, fmt.Errorf("%w: %s must be an array of time entries", ErrInvalidFile, key)
+			}
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no time entries found, expected one of: %s", ErrInvalidFile, strings.Join(keys, ", "))
+}
+
+// parseTogglJSON reads the Toggl Track detailed report JSON (entries under "data") or
+// a list of time entries. Durations are in milliseconds ("dur") or seconds ("duration").
+func parseTogglJSON(r io.Reader) ([]TrackerEntry, error) {
+	items, err := parseJSONEntries(r, "data", "time_entries")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]TrackerEntry, len(items))
+	for i, item := range items {
+		entry := &entries[i]
+		entry.Row = i + 1
+		var e struct {
+			User        string     `json:"user"`
+			Email       string     `json:"email"`
+			Project     string     `json:"project"`
+			Task        string     `json:"task"`
+			Description string     `json:"description"`
+			Start       time.Time  `json:"start"`
+			End         *time.Time `json:"end"`
+			Stop        *time.Time `json:"stop"`
+			Dur         int64      `json:"dur"`
+			Duration    int64      `json:"duration"`
+		}
+		if err := json.Unmarshal(item, &e); err != nil {
+			entry.Errors = validation.Errors{{Field: "entry", Message: err.Error()}}
+			continue
+		}
+		entry.User, entry.Email = e.User, e.Email
+		entry.Project, entry.Task, entry.Description = e.Project, e.Task, e.Description
+		entry.Start = e.Start
+
+		switch {
+		case e.End != nil:
+			entry.End = *e.End
+		case e.Stop != nil:
+			entry.End = *e.Stop
+		case e.Dur > 0:
+			entry.End = e.Start.Add(time.Duration(e.Dur) * time.Millisecond)
+		case e.Duration > 0:
+			entry.End = e.Start.Add(time.Duration(e.Duration) * time.Second)
+		default:
+			// Toggl marks running timers with a negative duration.
+			entry.Errors = validation.Errors{{Field: "stop", Message: "running timers are not imported"}}
+		}
+	}
+	return entries, nil
+}
+
+// parseClockifyJSON reads the Clockify detailed report JSON (entries under
+// "timeentries") or a list of time entries with hydrated user, project and task names.
+func parseClockifyJSON(r io.Reader) ([]TrackerEntry, error) {
+	items, err := parseJSONEntries(r, "timeentries", "timeEntries")
+	if err != nil {
+		return nil, err
+	}
+
+	type named struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	entries := make([]TrackerEntry, len(items))
+	for i, item := range items {
+		entry := &entries[i]
+		entry.Row = i + 1
+		var e struct {
+			Description  string `json:"description"`
+			UserName     string `json:"userName"`
+			UserEmail    string `json:"userEmail"`
+			ProjectName  string `json:"projectName"`
+			TaskName     string `json:"taskName"`
+			User         *named `json:"user"`
+			Project      *named `json:"project"`
+			Task         *named `json:"task"`
+			TimeInterval struct {
+				Start time.Time  `json:"start"`
+				End   *time.Time `json:"end"`
+			} `json:"timeInterval"`
+		}
+		if err := json.Unmarshal(item, &e); err != nil {
+			entry.Errors = validation.Errors{{Field: "entry", Message: err.Error()}}
+			continue
+		}
+		entry.User, entry.Email = e.UserName, e.UserEmail
+		if e.User != nil {
+			entry.User, entry.Email = firstNonEmpty(entry.User, e.User.Name), firstNonEmpty(entry.Email, e.User.Email)
+		}
+		entry.Project, entry.Task = e.ProjectName, e.TaskName
+		if e.Project != nil {
+			entry.Project = firstNonEmpty(entry.Project, e.Project.Name)
+		}
+		if e.Task != nil {
+			entry.Task = firstNonEmpty(entry.Task, e.Task.Name)
+		}
+		entry.Description = e.Description
+		entry.Start = e.TimeInterval.Start
+		if e.TimeInterval.End == nil {
+			entry.Errors = validation.Errors{{Field: "timeInterval.end", Message: "running timers are not imported"}}
+			continue
+		}
+		entry.End = *e.TimeInterval.End
+	}
+	return entries, nil
+}
+
+// parseHarvestJSON reads the Harvest API time entries export (entries under
+// "time_entries"). Start and end times are only present when the account tracks
+// timestamps; otherwise the entries are placed like CSV ones.
+func parseHarvestJSON(r io.Reader, options TrackerParseOptions) ([]TrackerEntry, error) {
+	items, err := parseJSONEntries(r, "time_entries")
+	if err != nil {
+		return nil, err
+	}
+
+	type named struct {
+		Name string `json:"name"`
+	}
+	entries := make([]TrackerEntry, len(items))
+	for i, item := range items {
+		entry := &entries[i]
+		entry.Row = i + 1
+		var e struct {
+			SpentDate   string  `json:"spent_date"`
+			Hours       float64 `json:"hours"`
+			Notes       string  `json:"notes"`
+			StartedTime string  `json:"started_time"`
+			EndedTime   string  `json:"ended_time"`
+			IsRunning   bool    `json:"is_running"`
+			User        named   `json:"user"`
+			Project     named   `json:"project"`
+			Task        named   `json:"task"`
+		}
+		if err := json.Unmarshal(item, &e); err != nil {
+			entry.Errors = validation.Errors{{Field: "entry", Message: err.Error()}}
+			continue
+		}
+		entry.User, entry.Project, entry.Task, entry.Description = e.User.Name, e.Project.Name, e.Task.Name, e.Notes
+		if e.IsRunning {
+			entry.Errors = validation.Errors{{Field: "is_running", Message: "running timers are not imported"}}
+			continue
+		}
+
+		if e.StartedTime != "" && e.EndedTime != "" {
+			if entry.Start, err = parseTrackerDateTime(e.SpentDate, e.StartedTime, options.Location); err != nil {
+				entry.Errors = validation.Errors{{Field: "started_time", Message: err.Error()}}
+				continue
+			}
+			if entry.End, err = parseTrackerDateTime(e.SpentDate, e.EndedTime, options.Location); err != nil {
+				entry.Errors = validation.Errors{{Field: "ended_time", Message: err.Error()}}
+			}
+			continue
+		}
+
+		date, err := parseTrackerDate(e.SpentDate, options.Location)
+		if err != nil {
+			entry.Errors = validation.Errors{{Field: "spent_date", Message: err.Error()}}
+			continue
+		}
+		if e.Hours <= 0 {
+			entry.Errors = validation.Errors{{Field: "hours", Message: "must be a positive number"}}
+			continue
+		}
+		entry.Start = date
+		entry.End = date.Add(hoursDuration(e.Hours))
+		entry.dateOnly = true
+	}
+	return entries, nil
+}
+
+// placeHarvestEntries gives entries that only have a date and hours a start time: the
+// first entry of a user's day starts at DayStart, the next ones follow it back to
+// back, in file order.
+func placeHarvestEntries(entries []TrackerEntry, options TrackerParseOptions) {
+	next := map[string]time.Time{}
+	for i := range entries {
+		entry := &entries[i]
+		if len(entry.Errors) > 0 || !entry.dateOnly {
+			continue
+		}
+		key := entry.User + "\x00" + entry.Start.Format("2006-01-02")
+		start, ok := next[key]
+		if !ok {
+			start = entry.Start.Add(options.DayStart)
+		}
+		length := entry.End.Sub(entry.Start)
+		entry.Start, entry.End = start, start.Add(length)
+		next[key] = entry.End
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var moscow = time.FixedZone("MSK", 3*60*60)
+
+// trackerEntry is the part of a parsed entry the tests check. Start and end are
+// RFC 3339 times; an entry that could not be read has errField set instead.
+type trackerEntry struct {
+	user, email, title string
+	start, end         string
+	errField           string
+}
+
+func parseFixture(t *testing.T, source, name string, options TrackerParseOptions) []TrackerEntry {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := ParseTrackerExport(source, f, options)
+	if err != nil {
+		t.Fatalf("ParseTrackerExport(%s, %s): %v", source, name, err)
+	}
+	return entries
+}
+
+func TestParseTrackerExport(t *testing.T) {
+	options := TrackerParseOptions{Location: moscow, DayStart: 9 * time.Hour}
+
+	tests := []struct {
+		source, file string
+		want         []trackerEntry
+	}{
+		{SourceToggl, "toggl.csv", []trackerEntry{
+			{user: "Иван Петров", email: "ivan.petrov@example.com", title: "Website / Frontend",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T11:30:00+03:00"},
+			{user: "Иван Петров", email: "ivan.petrov@example.com", title: "Website",
+				start: "2024-07-01T13:00:00+03:00", end: "2024-07-01T13:45:00+03:00"},
+			// The end date is read too, so an entry may run past midnight.
+			{user: "Jane Doe", email: "jane@example.com", title: "Internal / Meetings",
+				start: "2024-07-01T23:30:00+03:00", end: "2024-07-02T00:15:00+03:00"},
+		}},
+		{SourceToggl, "toggl.json", []trackerEntry{
+			// JSON times carry their own offset, whatever the location.
+			{user: "Иван Петров", email: "ivan.petrov@example.com", title: "Website / Frontend",
+				start: "2024-07-01T06:00:00Z", end: "2024-07-01T08:30:00Z"},
+			{user: "Jane Doe", title: "Internal",
+				start: "2024-07-01T20:30:00Z", end: "2024-07-01T21:15:00Z"},
+		}},
+		{SourceClockify, "clockify.csv", []trackerEntry{
+			{user: "Иван Петров", email: "ivan.petrov@example.com", title: "Website / Frontend",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T11:30:00+03:00"},
+			{user: "Jane Doe", email: "jane@example.com", title: "Internal / Meetings",
+				start: "2024-07-01T15:00:00+03:00", end: "2024-07-01T15:45:00+03:00"},
+		}},
+		{SourceClockify, "clockify.json", []trackerEntry{
+			{user: "Иван Петров", email: "ivan.petrov@example.com", title: "Website / Frontend",
+				start: "2024-07-01T06:00:00Z", end: "2024-07-01T08:30:00Z"},
+			{user: "Jane Doe", email: "jane@example.com", title: "Internal / Meetings",
+				start: "2024-07-01T12:00:00Z", end: "2024-07-01T12:45:00Z"},
+			{user: "Jane Doe", errField: "timeInterval.end"},
+		}},
+		{SourceHarvest, "harvest.csv", []trackerEntry{
+			// Entries without start times follow each other from the start of the day.
+			{user: "Иван Петров", title: "Website / Frontend",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T11:30:00+03:00"},
+			{user: "Иван Петров", title: "Website / Code review",
+				start: "2024-07-01T11:30:00+03:00", end: "2024-07-01T12:15:00+03:00"},
+			{user: "Jane Doe", title: "Internal / Meetings",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T09:45:00+03:00"},
+		}},
+		{SourceHarvest, "harvest.json", []trackerEntry{
+			{user: "Иван Петров", title: "Website / Frontend",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T11:30:00+03:00"},
+			{user: "Jane Doe", title: "Internal / Meetings",
+				start: "2024-07-01T09:00:00+03:00", end: "2024-07-01T09:45:00+03:00"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			entries := parseFixture(t, tt.source, tt.file, options)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, want := range tt.want {
+				entry := entries[i]
+				if entry.User != want.user {
+					t.Errorf("entry %d: user = %q, want %q", i, entry.User, want.user)
+				}
+				if want.errField != "" {
+					if len(entry.Errors) == 0 || entry.Errors[0].Field != want.errField {
+						t.Errorf("entry %d: errors = %v, want an error for %s", i, entry.Errors, want.errField)
+					}
+					continue
+				}
+				if len(entry.Errors) > 0 {
+					t.Errorf("entry %d: unexpected errors: %v", i, entry.Errors)
+					continue
+				}
+				if entry.Email != want.email {
+					t.Errorf("entry %d: email = %q, want %q", i, entry.Email, want.email)
+				}
+				if title := trackerTitle(entry); title != want.title {
+					t.Errorf("entry %d: title = %q, want %q", i, title, want.title)
+				}
+				checkTime(t, i, "start", entry.Start, want.start)
+				checkTime(t, i, "end", entry.End, want.end)
+			}
+		})
+	}
+}
+
+func checkTime(t *testing.T, i int, name string, got time.Time, want string) {
+	t.Helper()
+	wantTime, err := time.Parse(time.RFC3339, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(wantTime) {
+		t.Errorf("entry %d: %s = %s, want %s", i, name, got.Format(time.RFC3339), want)
+	}
+}
+
+func TestParseTrackerExportUnsupportedSource(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "toggl.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := ParseTrackerExport("jira", f, TrackerParseOptions{}); err == nil {
+		t.Error("ParseTrackerExport accepted an unsupported source")
+	}
+}
+
+func TestParseTrackerOptions(t *testing.T) {
+	options, err := ParseTrackerOptions("UTC", "08:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Location != time.UTC || options.DayStart != 8*time.Hour+30*time.Minute {
+		t.Errorf("got %v and %v, want UTC and 8h30m", options.Location, options.DayStart)
+	}
+
+	for _, tt := range []struct{ timezone, dayStart string }{
+		{"Mars/Olympus", ""},
+		{"", "9am"},
+	} {
+		if _, err := ParseTrackerOptions(tt.timezone, tt.dayStart); err == nil {
+			t.Errorf("ParseTrackerOptions(%q, %q) succeeded, want an error", tt.timezone, tt.dayStart)
+		}
+	}
+}
+
+func TestResolveTrackerUser(t *testing.T) {
+	mapping := map[string]int{"jane@example.com": 3, "иван петров": 5}
+	// Names matched in the database before are taken from the cache.
+	matched := map[string]userMatch{"john smith": {id: 7}}
+
+	tests := []struct {
+		name    string
+		entry   TrackerEntry
+		wantID  int
+		problem bool
+	}{
+		{"email first", TrackerEntry{User: "Иван Петров", Email: "Jane@Example.com"}, 3, false},
+		{"name ignoring case", TrackerEntry{User: "ИВАН ПЕТРОВ"}, 5, false},
+		{"cached match", TrackerEntry{User: "John Smith", Email: "john@example.com"}, 7, false},
+		{"single word", TrackerEntry{User: "admin"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := resolveTrackerUser(tt.entry, mapping, matched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match.id != tt.wantID || (match.problem != "") != tt.problem {
+				t.Errorf("got %+v, want id %d", match, tt.wantID)
+			}
+		})
+	}
+}
@@ -10,6 +10,10 @@ import (
 var Logger *zap.Logger
 
 func InitLogger() {
+	// The log is not tracked by git, so a fresh checkout may lack its directory.
+	if err := os.MkdirAll("internal/logger/logs", 0755); err != nil {
+		log.Fatalf("Failed to create log directory: %v", err)
+	}
 	file, err := os.OpenFile("internal/logger/logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatalf("Failed to open log file: %v", err)
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditUserCreate    = "user.create"
+	AuditUserUpdate    = "user.update"
+	AuditUserDelete    = "user.delete"
+	AuditUserExport    = "user.export"
+	AuditUserAnonymize = "user.anonymize"
+	AuditTimerStart    = "timer.start"
+	AuditTimerStop     = "timer.stop"
+	AuditTaskImport    = "task.import"
+
+	AuditCalendarTokenCreate = "calendar_token.create"
+	AuditCalendarTokenRevoke = "calendar_token.revoke"
+
+	AuditWebhookCreate = "webhook.create"
+	AuditWebhookUpdate = "webhook.update"
+	AuditWebhookDelete = "webhook.delete"
+)
+
+const (
+	AuditTargetUser    = "user"
+	AuditTargetTask    = "task"
+	AuditTargetWebhook = "webhook"
+)
+
+// Actor is who performs a change, as recorded in the audit log. Name is established by
+// the server: the remote address of an API client, or the operator given to a
+// command-line tool. Claimed is the name an API client gives for itself in X-Actor; it
+// is not verified, so it is only kept alongside and never stands in for Name.
+type Actor struct {
+	Name    string
+	Claimed string
+}
+
+// maxActorLength is the size of the actor columns of the audit log.
+const maxActorLength = 100
+
+// NewActor returns the actor of an API request, cutting the claimed name to fit the
+// audit log.
+func NewActor(address, claimed string) Actor {
+	if runes := []rune(claimed); len(runes) > maxActorLength {
+		claimed = string(runes[:maxActorLength])
+	}
+	return Actor{Name: address, Claimed: claimed}
+}
+
+type AuditEntry struct {
+	ID           int             `json:"id"`
+	Actor        string          `json:"actor"`
+	ClaimedActor string          `json:"claimed_actor,omitempty"`
+	Action       string          `json:"action"`
+	TargetType   string          `json:"target_type"`
+	TargetID     int             `json:"target_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+type AuditFilter struct {
+	Actor        string
+	ClaimedActor string
+	Action       string
+	TargetType   string
+	TargetID     int
+	From         time.Time
+	To           time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time-tracker/internal/validation"
+
+// BulkImportRow is the outcome of one CSV row. Row numbers count the header as row 1,
+// as spreadsheets do.
+type BulkImportRow struct {
+	Row    int               `json:"row"`
+	Status string            `json:"status"`
+	ID     int               `json:"id,omitempty"`
+	Errors validation.Errors `json:"errors,omitempty"`
+}
+
+type BulkImportReport struct {
+	DryRun   bool            `json:"dry_run"`
+	Total    int             `json:"total"`
+	New      int             `json:"new"`
+	Exists   int             `json:"exists"`
+	Invalid  int             `json:"invalid"`
+	Imported int             `json:"imported"`
+	Rows     []BulkImportRow `json:"rows"`
+}
@@ -0,0 +1,37 @@
+package models
+
+const (
+	ImportStatusNew      = "new"
+	ImportStatusConflict = "conflict"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusImported = "imported"
+	ImportStatusExists   = "exists"
+	ImportStatusInvalid  = "invalid"
+)
+
+// CalendarImportRule maps calendar event summaries to task titles. Pattern is a
+// case-insensitive regular expression; Title may refer to its groups as $1, $2...
+// An empty Title keeps the summary.
+type CalendarImportRule struct {
+	Pattern     string `json:"pattern"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type CalendarImportEntry struct {
+	UID       string `json:"uid"`
+	Summary   string `json:"summary"`
+	Task      Task   `json:"task"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	Conflicts []int  `json:"conflicts,omitempty"`
+}
+
+type CalendarImportReport struct {
+	DryRun    bool                  `json:"dry_run"`
+	New       int                   `json:"new"`
+	Conflicts int                   `json:"conflicts"`
+	Skipped   int                   `json:"skipped"`
+	Imported  int                   `json:"imported"`
+	Entries   []CalendarImportEntry `json:"entries"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type TaskRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
@@ -1,5 +1,18 @@
 package models
 
+// UserRequest is the body that adds a user. The passport number is passport_number,
+// as in every other JSON body of the API; passportNumber is still accepted from
+// clients written before the names were unified.
 type UserRequest struct {
-	PassportNumber string `json:"passportNumber"`
+	PassportNumber       string `json:"passport_number"`
+	LegacyPassportNumber string `json:"passportNumber,omitempty"`
+}
+
+// Passport returns the passport number of the request and the field it was given in,
+// which validation errors refer to.
+func (r UserRequest) Passport() (number, field string) {
+	if r.PassportNumber == "" && r.LegacyPassportNumber != "" {
+		return r.LegacyPassportNumber, "passportNumber"
+	}
+	return r.PassportNumber, "passport_number"
 }
@@ -0,0 +1,44 @@
+package models
+
+import "math"
+
+// Cursor is the decoded form of an opaque keyset pagination cursor.
+// It holds the sort key of the row the page starts after (or before, when Backward is set).
+type Cursor struct {
+	SortBy   string `json:"s,omitempty"`
+	Desc     bool   `json:"d,omitempty"`
+	Value    string `json:"v,omitempty"`
+	ID       int    `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// PageRequest selects a page either by offset or, when Keyset is set, by keyset.
+// A keyset request without a Cursor starts from the beginning of the listing.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Keyset bool
+	Cursor *Cursor
+}
+
+// MaxPageSize is the largest page a listing returns; larger page sizes are capped.
+const MaxPageSize = 100
+
+// OffsetPage selects a page by its number, counted from 1. The page size is capped at
+// MaxPageSize, and page numbers too large for an offset are lowered to one that is
+// still past the end of any listing.
+func OffsetPage(page, pageSize int) PageRequest {
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	if maxPage := math.MaxInt32 / pageSize; page > maxPage {
+		page = maxPage
+	}
+	return PageRequest{Limit: pageSize, Offset: (page - 1) * pageSize}
+}
+
+type PageInfo struct {
+	Total int    `json:"total"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
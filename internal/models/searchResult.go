@@ -0,0 +1,16 @@
+package models
+
+type UserSearchResult struct {
+	User
+	Rank float64 `json:"rank"`
+}
+
+type TaskSearchResult struct {
+	Task
+	Rank float64 `json:"rank"`
+}
+
+type SearchResults struct {
+	Users []UserSearchResult `json:"users"`
+	Tasks []TaskSearchResult `json:"tasks"`
+}
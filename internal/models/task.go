@@ -3,8 +3,26 @@ package models
 import "time"
 
 type Task struct {
-	UserID    int
-	TaskID    int
-	StartTime time.Time
-	EndTime   time.Time
+	UserID      int       `json:"user_id"`
+	TaskID      int       `json:"task_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Version     int       `json:"version,omitempty"`
+}
+
+type TaskFilter struct {
+	UserID int
+	Start  time.Time
+	End    time.Time
+}
+
+// WallClock interprets a task time in the server's time zone. Task times are stored
+// without a zone, so only their wall clock reading is meaningful.
+func WallClock(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
 }
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// The types of timer events. Timers cannot be paused, so there is no pause event: a
+// break is a stop followed by a new start.
+const (
+	TimerStarted = "timer.started"
+	TimerStopped = "timer.stopped"
+	TimerTick    = "timer.tick"
+)
+
+// Timer is a time entry together with the team of its user, as shown on live dashboards.
+type Timer struct {
+	TaskID    int        `json:"task_id"`
+	UserID    int        `json:"user_id"`
+	Team      string     `json:"team,omitempty"`
+	Title     string     `json:"title"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+}
+
+// TimerEvent is pushed to live update subscribers. Ticks are sent periodically for
+// every running timer, so clients can show elapsed time without keeping a clock.
+type TimerEvent struct {
+	Type string `json:"type"`
+	Timer
+	ElapsedSeconds int64     `json:"elapsed_seconds"`
+	At             time.Time `json:"at"`
+}
+
+func NewTimerEvent(eventType string, timer Timer, at time.Time) TimerEvent {
+	end := at
+	if timer.EndTime != nil {
+		end = *timer.EndTime
+	}
+	return TimerEvent{Type: eventType, Timer: timer, ElapsedSeconds: int64(end.Sub(timer.StartTime).Seconds()), At: at}
+}
+
+type TimerFilter struct {
+	UserID int
+	Team   string
+}
+
+func (f TimerFilter) Match(timer Timer) bool {
+	if f.UserID != 0 && timer.UserID != f.UserID {
+		return false
+	}
+	if f.Team != "" && timer.Team != f.Team {
+		return false
+	}
+	return true
+}
@@ -7,4 +7,26 @@ type User struct {
 	Patronymic     string `json:"patronymic"`
 	Address        string `json:"address"`
 	PassportNumber string `json:"passport_number"`
+	Team           string `json:"team"`
+	Version        int    `json:"version,omitempty"`
+}
+
+// SortValue returns the value of the field a listing is sorted by, as kept in keyset
+// cursors. Listings sorted by id keep no value.
+func (u User) SortValue(sortBy string) string {
+	switch sortBy {
+	case "surname":
+		return u.Surname
+	case "name":
+		return u.Name
+	case "patronymic":
+		return u.Patronymic
+	case "team":
+		return u.Team
+	case "passportNumber":
+		return u.PassportNumber
+	case "address":
+		return u.Address
+	}
+	return ""
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// UserDataExport is everything stored about a user, handed out on a personal data request.
+type UserDataExport struct {
+	ExportedAt   time.Time    `json:"exported_at"`
+	Profile      User         `json:"profile"`
+	TimeEntries  []Task       `json:"time_entries"`
+	AuditEntries []AuditEntry `json:"audit_entries"`
+}
@@ -22,7 +22,7 @@ func CalculateUserEffort(tasks []Task) []UserEffort {
 		duration := task.EndTime.Sub(task.StartTime)
 
 		userEffort.Hours = int(duration.Hours())
-		userEffort.Minutes = int(duration.Minutes())
+		userEffort.Minutes = int(duration.Minutes()) % 60
 		userEfforts = append(userEfforts, userEffort)
 	}
 	return userEfforts
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+)
+
+// UserSortFields maps the sortBy values accepted by the API to users table columns.
+// Nullable columns are coalesced so they can take part in keyset comparisons.
+var UserSortFields = map[string]string{
+	"id":         "id",
+	"surname":    "surname",
+	"name":       "name",
+	"patronymic": "COALESCE(patronymic, '')",
+	"team":       "COALESCE(team, '')",
+}
+
+// UserDecryptedSortFields are the sortBy values whose columns are encrypted, so users
+// are sorted on them after decryption.
+var UserDecryptedSortFields = map[string]bool{
+	"passportNumber": true,
+	"address":        true,
+}
+
+type UserFilter struct {
+	IDs            []int
+	Surname        string
+	Name           string
+	Patronymic     string
+	Address        string
+	PassportNumber string
+	Team           string
+	// Match controls how Surname, Name, Patronymic and Address are compared: exact
+	// equality or case-insensitive prefix/substring search.
+	Match string
+	// Query is a free-text search over surname, name and patronymic.
+	Query    string
+	SortBy   string
+	SortDesc bool
+}
+
+func (f *UserFilter) Validate() error {
+	switch f.Match {
+	case "", MatchExact, MatchPrefix, MatchContains:
+	default:
+		return fmt.Errorf("invalid match mode: %s", f.Match)
+	}
+	if f.SortBy != "" {
+		if _, ok := UserSortFields[f.SortBy]; !ok && !UserDecryptedSortFields[f.SortBy] {
+			return fmt.Errorf("invalid sort field: %s", f.SortBy)
+		}
+	}
+	return nil
+}
+
+// PartialAddress reports whether the address is searched by prefix or substring, which
+// the encrypted column cannot answer.
+func (f *UserFilter) PartialAddress() bool {
+	return f.Address != "" && (f.Match == MatchPrefix || f.Match == MatchContains)
+}
+
+// NeedsDecryption reports whether users have to be decrypted before they can be
+// filtered and ordered.
+func (f *UserFilter) NeedsDecryption() bool {
+	return f.PartialAddress() || UserDecryptedSortFields[f.SortBy]
+}
+
+// MatchesAddress reports whether address satisfies the partial address filter.
+func (f *UserFilter) MatchesAddress(address string) bool {
+	address, search := strings.ToLower(address), strings.ToLower(f.Address)
+	if f.Match == MatchPrefix {
+		return strings.HasPrefix(address, search)
+	}
+	return strings.Contains(address, search)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time-tracker/internal/validation"
+)
+
+// OptionalString is a field of a partial update. Set tells whether the field was given
+// at all, Null whether it was given as null to clear the stored value.
+type OptionalString struct {
+	Set   bool
+	Null  bool
+	Value string
+}
+
+func (s *OptionalString) UnmarshalJSON(data []byte) error {
+	s.Set = true
+	if string(data) == "null" {
+		s.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &s.Value)
+}
+
+// Clear tells whether the field is to be set to NULL. An empty string clears it too.
+func (s OptionalString) Clear() bool {
+	return s.Set && (s.Null || s.Value == "")
+}
+
+// UserUpdate changes the editable fields of a user with JSON Merge Patch semantics:
+// absent fields are left unchanged and null clears a field. Only the patronymic and
+// the team can be cleared.
+type UserUpdate struct {
+	Surname        OptionalString `json:"surname"`
+	Name           OptionalString `json:"name"`
+	Patronymic     OptionalString `json:"patronymic"`
+	Address        OptionalString `json:"address"`
+	PassportNumber OptionalString `json:"passport_number"`
+	Team           OptionalString `json:"team"`
+}
+
+// Empty tells whether the update has no fields to change.
+func (u UserUpdate) Empty() bool {
+	return !u.Surname.Set && !u.Name.Set && !u.Patronymic.Set && !u.Address.Set && !u.PassportNumber.Set && !u.Team.Set
+}
+
+// Validate checks that required fields are not cleared and normalizes the passport
+// number. passportField names the passport number in the errors, as it is spelled
+// differently in query strings and JSON bodies.
+func (u *UserUpdate) Validate(passportField string) validation.Errors {
+	var errs validation.Errors
+	required := []struct {
+		field string
+		value *OptionalString
+	}{
+		{"surname", &u.Surname},
+		{"name", &u.Name},
+		{"address", &u.Address},
+	}
+	for _, f := range required {
+		if f.value.Set && (f.value.Null || strings.TrimSpace(f.value.Value) == "") {
+			errs = append(errs, validation.FieldError{Field: f.field, Message: f.field + " cannot be cleared"})
+		}
+	}
+
+	if u.PassportNumber.Set {
+		if u.PassportNumber.Null {
+			errs = append(errs, validation.FieldError{Field: passportField, Message: "passport number cannot be cleared"})
+		} else if passport, fieldErr := validation.NormalizePassport(passportField, u.PassportNumber.Value); fieldErr != nil {
+			errs = append(errs, *fieldErr)
+		} else {
+			u.PassportNumber.Value = passport
+		}
+	}
+	return errs
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOptionalStringUnmarshal(t *testing.T) {
+	tests := []struct {
+		body  string
+		want  OptionalString
+		clear bool
+	}{
+		{`{}`, OptionalString{}, false},
+		{`{"patronymic": null}`, OptionalString{Set: true, Null: true}, true},
+		{`{"patronymic": ""}`, OptionalString{Set: true}, true},
+		{`{"patronymic": "Иванович"}`, OptionalString{Set: true, Value: "Иванович"}, false},
+	}
+	for _, tt := range tests {
+		var update UserUpdate
+		if err := json.Unmarshal([]byte(tt.body), &update); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if update.Patronymic != tt.want || update.Patronymic.Clear() != tt.clear {
+			t.Errorf("%s: got %+v (clear %v), want %+v (clear %v)", tt.body, update.Patronymic, update.Patronymic.Clear(), tt.want, tt.clear)
+		}
+	}
+
+	var update UserUpdate
+	if err := json.Unmarshal([]byte(`{"patronymic": 5}`), &update); err == nil {
+		t.Error("a number for a string field was accepted")
+	}
+}
+
+func TestUserRequestPassport(t *testing.T) {
+	tests := []struct {
+		body          string
+		number, field string
+	}{
+		{`{"passport_number": "1234 567890"}`, "1234 567890", "passport_number"},
+		{`{"passportNumber": "1234 567890"}`, "1234 567890", "passportNumber"},
+		{`{"passport_number": "1111 111111", "passportNumber": "1234 567890"}`, "1111 111111", "passport_number"},
+		{`{}`, "", "passport_number"},
+	}
+	for _, tt := range tests {
+		var req UserRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if number, field := req.Passport(); number != tt.number || field != tt.field {
+			t.Errorf("%s: Passport() = %q, %q, want %q, %q", tt.body, number, field, tt.number, tt.field)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+	"time-tracker/internal/validation"
+)
+
+const (
+	WebhookTimerStarted   = TimerStarted
+	WebhookTimerStopped   = TimerStopped
+	WebhookUserCreated    = "user.created"
+	WebhookUserUpdated    = "user.updated"
+	WebhookUserDeleted    = "user.deleted"
+	WebhookUserAnonymized = "user.anonymized"
+)
+
+var WebhookEvents = []string{
+	WebhookTimerStarted,
+	WebhookTimerStopped,
+	WebhookUserCreated,
+	WebhookUserUpdated,
+	WebhookUserDeleted,
+	WebhookUserAnonymized,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a subscription to lifecycle events. An empty Events list subscribes to
+// all of them. The signing secret is never returned after creation.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookRequest creates or updates a webhook. On update, absent fields keep their
+// values.
+type WebhookRequest struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+	Secret *string   `json:"secret"`
+}
+
+func (r WebhookRequest) Validate(create bool) validation.Errors {
+	var errs validation.Errors
+	if r.URL == nil && create {
+		errs = append(errs, validation.FieldError{Field: "url", Message: "url is required"})
+	}
+	if r.URL != nil {
+		u, err := url.Parse(*r.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, validation.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+		} else if !validation.PublicHost(u.Hostname()) {
+			errs = append(errs, validation.FieldError{Field: "url", Message: "must not point to a loopback, link-local or private address"})
+		}
+	}
+	if r.Events != nil {
+		for _, event := range *r.Events {
+			if !isWebhookEvent(event) {
+				errs = append(errs, validation.FieldError{Field: "events", Message: fmt.Sprintf("unknown event: %s", event)})
+			}
+		}
+	}
+	if r.Secret != nil && len(*r.Secret) < 16 {
+		errs = append(errs, validation.FieldError{Field: "secret", Message: "must be at least 16 characters"})
+	}
+	return errs
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook, with the outcome of its latest
+// delivery attempt.
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body posted to webhooks.
+type WebhookPayload struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookUser is the user data sent to webhooks. Passport number and address are left
+// out: the outbox would otherwise keep them unencrypted.
+type WebhookUser struct {
+	ID         int    `json:"id"`
+	Surname    string `json:"surname"`
+	Name       string `json:"name"`
+	Patronymic string `json:"patronymic"`
+	Team       string `json:"team"`
+}
+
+func NewWebhookUser(user User) WebhookUser {
+	return WebhookUser{ID: user.ID, Surname: user.Surname, Name: user.Name, Patronymic: user.Patronymic, Team: user.Team}
+}
+
+type DeliveryFilter struct {
+	WebhookID int
+	Status    string
+}
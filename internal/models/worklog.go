@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Worklog is the JSON form of a user's worklog: the completed time entries of the
+// period in chronological order.
+type Worklog struct {
+	UserID       int        `json:"user_id"`
+	PeriodStart  *time.Time `json:"period_start,omitempty"`
+	PeriodEnd    *time.Time `json:"period_end,omitempty"`
+	Entries      []Task     `json:"entries"`
+	TotalSeconds int64      `json:"total_seconds"`
+}
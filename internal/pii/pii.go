@@ -0,0 +1,125 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time-tracker/internal/config"
+	"unicode/utf8"
+)
+
+var (
+	currentKeyID  string
+	ciphers       map[string]cipher.AEAD
+	blindIndexKey []byte
+)
+
+// ErrNotInitialized is returned when a value is encrypted before Init has loaded the keys.
+var ErrNotInitialized = errors.New("encryption keys are not loaded")
+
+// Init loads the field encryption keys and the blind index key.
+// Encryption keys are given as "id:base64key" pairs; the first one encrypts new
+// values, the others are kept to decrypt values written before a key rotation. The
+// keys in use are replaced only when all of the new ones are valid.
+func Init(cfg *config.Config) error {
+	if cfg.EncryptionKeys == "" || cfg.BlindIndexKey == "" {
+		return errors.New("ENCRYPTION_KEYS and BLIND_INDEX_KEY must be set")
+	}
+
+	aeads := make(map[string]cipher.AEAD)
+	current := ""
+	for _, pair := range strings.Split(cfg.EncryptionKeys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" {
+			return fmt.Errorf("invalid encryption key entry %q, expected id:base64key", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("encryption key %s must be 32 bytes encoded in base64", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		aeads[id] = aead
+		if current == "" {
+			current = id
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.BlindIndexKey)
+	if err != nil || len(key) < 32 {
+		return errors.New("blind index key must be at least 32 bytes encoded in base64")
+	}
+	currentKeyID, ciphers, blindIndexKey = current, aeads, key
+	return nil
+}
+
+// Encrypt seals the value with the current key. The result is "keyID:base64(nonce|ciphertext)".
+func Encrypt(plaintext string) (string, error) {
+	aead, ok := ciphers[currentKeyID]
+	if !ok {
+		return "", ErrNotInitialized
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return currentKeyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(value string) (string, error) {
+	id, encoded, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	aead, ok := ciphers[id]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key %s", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsCurrent reports whether the value is encrypted with the current key.
+func IsCurrent(value string) bool {
+	return strings.HasPrefix(value, currentKeyID+":")
+}
+
+// BlindIndex returns a deterministic keyed hash of the value, which allows equality
+// lookups and uniqueness constraints on encrypted columns.
+func BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, blindIndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Redact masks a personal value for log output, keeping only its last two characters.
+func Redact(value string) string {
+	length := utf8.RuneCountInString(value)
+	if length <= 4 {
+		return strings.Repeat("*", length)
+	}
+	runes := []rune(value)
+	return strings.Repeat("*", length-2) + string(runes[length-2:])
+}
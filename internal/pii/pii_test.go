@@ -0,0 +1,162 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time-tracker/internal/config"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func initKeys(t *testing.T, encryptionKeys string) {
+	t.Helper()
+	err := Init(&config.Config{EncryptionKeys: encryptionKeys, BlindIndexKey: testKey('b')})
+	if err != nil {
+		t.Fatalf("Init(%q): %v", encryptionKeys, err)
+	}
+}
+
+func TestEncryptBeforeInit(t *testing.T) {
+	currentKeyID, ciphers = "", nil
+	if _, err := Encrypt("1234 567890"); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Encrypt before Init: got %v, want ErrNotInitialized", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	initKeys(t, "1:"+testKey(1))
+
+	for _, plaintext := range []string{"1234 567890", "г. Москва, ул. Ленина, д. 5", ""} {
+		sealed, err := Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(sealed, "1:") || (plaintext != "" && strings.Contains(sealed, plaintext)) {
+			t.Errorf("Encrypt(%q) = %q", plaintext, sealed)
+		}
+		got, err := Decrypt(sealed)
+		if err != nil || got != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+
+	// A fresh nonce makes every encryption of the same value different.
+	first, _ := Encrypt("1234 567890")
+	second, _ := Encrypt("1234 567890")
+	if first == second {
+		t.Error("two encryptions of the same value are equal")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	initKeys(t, "1:"+testKey(1))
+	old, err := Encrypt("1234 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initKeys(t, "2:"+testKey(2)+", 1:"+testKey(1))
+	if IsCurrent(old) {
+		t.Error("a value sealed with key 1 is current after rotating to key 2")
+	}
+	if got, err := Decrypt(old); err != nil || got != "1234 567890" {
+		t.Errorf("Decrypt of a value sealed with the old key = %q, %v", got, err)
+	}
+	resealed, err := Encrypt("1234 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsCurrent(resealed) || !strings.HasPrefix(resealed, "2:") {
+		t.Errorf("Encrypt after rotation = %q, want it sealed with key 2", resealed)
+	}
+
+	// Once the old key is dropped, values still sealed with it cannot be read.
+	initKeys(t, "2:"+testKey(2))
+	if _, err := Decrypt(old); err == nil {
+		t.Error("Decrypt succeeded with the key removed")
+	}
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	initKeys(t, "1:"+testKey(1))
+	sealed, err := Encrypt("1234 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, encoded, _ := strings.Cut(sealed, ":")
+	raw, _ := base64.StdEncoding.DecodeString(encoded)
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := map[string]string{
+		"no key id":            encoded,
+		"not base64":           "1:not base64!",
+		"shorter than a nonce": "1:" + base64.StdEncoding.EncodeToString(raw[:5]),
+		"truncated":            "1:" + base64.StdEncoding.EncodeToString(raw[:len(raw)-1]),
+		"tampered":             "1:" + base64.StdEncoding.EncodeToString(tampered),
+		"unknown key":          "9:" + encoded,
+		"plaintext":            "1234 567890",
+	}
+	for name, value := range tests {
+		if got, err := Decrypt(value); err == nil {
+			t.Errorf("%s: Decrypt(%q) = %q, want an error", name, value, got)
+		}
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	initKeys(t, "1:"+testKey(1))
+	index := BlindIndex("1234 567890")
+	if len(index) != 64 {
+		t.Errorf("BlindIndex length = %d, want 64 hex characters", len(index))
+	}
+
+	// The index does not depend on the encryption keys, so rotation keeps lookups working.
+	initKeys(t, "2:"+testKey(2)+",1:"+testKey(1))
+	if got := BlindIndex("1234 567890"); got != index {
+		t.Errorf("BlindIndex changed across calls: %s and %s", index, got)
+	}
+	if BlindIndex("1234 567891") == index {
+		t.Error("different values have the same blind index")
+	}
+
+	err := Init(&config.Config{EncryptionKeys: "1:" + testKey(1), BlindIndexKey: testKey('c')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if BlindIndex("1234 567890") == index {
+		t.Error("different blind index keys give the same index")
+	}
+}
+
+func TestInitInvalid(t *testing.T) {
+	initKeys(t, "1:"+testKey(1))
+	sealed, err := Encrypt("1234 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []config.Config{
+		{},
+		{EncryptionKeys: "1:" + testKey(1)},
+		{EncryptionKeys: testKey(1), BlindIndexKey: testKey('b')},
+		{EncryptionKeys: "2:" + base64.StdEncoding.EncodeToString([]byte("short")), BlindIndexKey: testKey('b')},
+		{EncryptionKeys: "2:" + testKey(2), BlindIndexKey: base64.StdEncoding.EncodeToString([]byte("short"))},
+	}
+	for _, cfg := range tests {
+		cfg := cfg
+		if err := Init(&cfg); err == nil {
+			t.Errorf("Init(%+v) succeeded, want an error", cfg)
+		}
+	}
+
+	// A rejected configuration leaves the keys loaded before in place.
+	if got, err := Decrypt(sealed); err != nil || got != "1234 567890" || !IsCurrent(sealed) {
+		t.Errorf("after a failed Init: Decrypt = %q, %v, current %v", got, err, IsCurrent(sealed))
+	}
+}
@@ -0,0 +1,121 @@
+// Package ratelimit keeps a token bucket per key, such as a client or a client and a
+// route, to limit how often each of them may do something.
+package ratelimit
+
+import (
+	"fmt"
+	"golang.org/x/time/rate"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Requests per period Per, in bursts of up to Requests. The zero Rate is
+// unlimited.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+var periods = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+// ParseRate reads a rate written as requests per period, such as "100/m"; the period is
+// s, m or h. "off" and "0" disable the limit.
+func ParseRate(value string) (Rate, error) {
+	if value == "off" || value == "0" {
+		return Rate{}, nil
+	}
+	count, period, ok := strings.Cut(value, "/")
+	requests, err := strconv.Atoi(count)
+	per, known := periods[period]
+	if !ok || err != nil || requests < 1 || !known {
+		return Rate{}, fmt.Errorf("invalid rate %q: want requests per period such as 100/m", value)
+	}
+	return Rate{Requests: requests, Per: per}, nil
+}
+
+func (r Rate) unlimited() bool {
+	return r.Requests == 0
+}
+
+// ExceededError is returned when a limit is reached. RetryAfter is how long until the
+// next request would be allowed.
+type ExceededError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry in %d seconds", RetryAfterSeconds(e.RetryAfter))
+}
+
+// RetryAfterSeconds rounds a delay up to whole seconds for the Retry-After header.
+func RetryAfterSeconds(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
+
+// Reject answers a request that exceeded a limit with 429 Too Many Requests.
+func Reject(w http.ResponseWriter, err *ExceededError) {
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(err.RetryAfter)))
+	http.Error(w, "Too many requests: "+err.Error(), http.StatusTooManyRequests)
+}
+
+// Limiter holds the buckets of one limit. Buckets of keys that have been idle long
+// enough to refill completely are dropped, as they are no different from new ones.
+type Limiter struct {
+	rate Rate
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func New(r Rate) *Limiter {
+	return &Limiter{rate: r, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// Allow takes a token from the bucket of key. When there is none it returns an
+// ExceededError and takes nothing, so rejected requests do not push the limit further
+// away.
+func (l *Limiter) Allow(key string) error {
+	if l.rate.unlimited() {
+		return nil
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Every(l.rate.Per/time.Duration(l.rate.Requests)), l.rate.Requests)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	l.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return &ExceededError{RetryAfter: delay}
+	}
+	return nil
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Per {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.rate.Per {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Rate
+		wantErr bool
+	}{
+		{"100/m", Rate{Requests: 100, Per: time.Minute}, false},
+		{"5/s", Rate{Requests: 5, Per: time.Second}, false},
+		{"1000/h", Rate{Requests: 1000, Per: time.Hour}, false},
+		{"off", Rate{}, false},
+		{"0", Rate{}, false},
+		{"", Rate{}, true},
+		{"100", Rate{}, true},
+		{"100/d", Rate{}, true},
+		{"0/m", Rate{}, true},
+		{"-1/m", Rate{}, true},
+		{"ten/m", Rate{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %+v, %v, want %+v (error: %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
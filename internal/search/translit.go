@@ -0,0 +1,108 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// latinToCyrillic is matched longest first, so digraphs win over single letters.
+var latinToCyrillic = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"a", "а"}, {"b", "б"}, {"c", "ц"}, {"d", "д"}, {"e", "е"}, {"f", "ф"},
+	{"g", "г"}, {"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"},
+	{"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"v", "в"}, {"w", "в"}, {"x", "кс"},
+	{"z", "з"},
+}
+
+// Variants returns the lower-cased query together with its Cyrillic-to-Latin and
+// Latin-to-Cyrillic transliterations, so "Ivanov" also finds "Иванов" and vice versa.
+func Variants(query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	variants := []string{query}
+	for _, variant := range []string{toLatin(query), toCyrillic(query)} {
+		if !contains(variants, variant) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+func toLatin(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func toCyrillic(s string) string {
+	var b strings.Builder
+	var prev rune
+	for i := 0; i < len(s); {
+		matched := false
+		// "y" is "й" after a vowel (Sergey, Aleksey) and "ы" elsewhere (Ryzhov).
+		if s[i] == 'y' && !strings.HasPrefix(s[i:], "yu") && !strings.HasPrefix(s[i:], "ya") &&
+			!strings.HasPrefix(s[i:], "yo") && !strings.HasPrefix(s[i:], "ye") {
+			if strings.ContainsRune("aeiouаеёиоуыэюя", prev) {
+				b.WriteString("й")
+				prev = 'й'
+			} else {
+				b.WriteString("ы")
+				prev = 'ы'
+			}
+			i++
+			continue
+		}
+		for _, pair := range latinToCyrillic {
+			if strings.HasPrefix(s[i:], pair.latin) {
+				b.WriteString(pair.cyrillic)
+				prev = []rune(pair.cyrillic)[len([]rune(pair.cyrillic))-1]
+				i += len(pair.latin)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r := []rune(s[i:])[0]
+			b.WriteRune(r)
+			if unicode.IsLetter(r) {
+				prev = r
+			} else {
+				prev = 0
+			}
+			i += len(string(r))
+		}
+	}
+	return b.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
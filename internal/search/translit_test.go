@@ -0,0 +1,44 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Ivanov", []string{"ivanov", "иванов"}},
+		{"Иванов", []string{"иванов", "ivanov"}},
+		{" Alexey Petrov ", []string{"alexey petrov", "алексей петров"}},
+		{"Юля", []string{"юля", "yulya"}},
+		// Digits read the same in both alphabets, so there is nothing to add.
+		{"123", []string{"123"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := Variants(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Variants(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		latin, want string
+	}{
+		{"sergey", "сергей"},
+		{"ryzhov", "рыжов"},
+		{"shchukin", "щукин"},
+		{"yulya", "юля"},
+		{"maxim", "максим"},
+		{"anna-maria", "анна-мариа"},
+	}
+	for _, tt := range tests {
+		if got := toCyrillic(tt.latin); got != tt.want {
+			t.Errorf("toCyrillic(%q) = %q, want %q", tt.latin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/events"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+)
+
+// StartTimer starts a time entry for the user and returns its ID.
+func StartTimer(actor models.Actor, userID int, taskReq models.TaskRequest) (int, error) {
+	taskID, err := database.StartTaskTimer(actor, userID, taskReq)
+	if err != nil {
+		return 0, err
+	}
+	publishTimerEvent(models.TimerStarted, taskID)
+	return taskID, nil
+}
+
+// StopTimer stops a running time entry. A non-zero version makes the change
+// conditional, as in database.StopTaskTimer.
+func StopTimer(actor models.Actor, taskID, version int) error {
+	if err := database.StopTaskTimer(actor, taskID, version); err != nil {
+		return err
+	}
+	publishTimerEvent(models.TimerStopped, taskID)
+	return nil
+}
+
+// publishTimerEvent notifies live update subscribers of a timer change. Failing to
+// publish does not fail the request: the change itself has already been committed.
+func publishTimerEvent(eventType string, taskID int) {
+	timer, err := database.GetTimer(taskID)
+	if err != nil {
+		logger.Logger.Error("Error getting timer for live update", zap.Int("taskID", taskID), zap.Error(err))
+		return
+	}
+	events.Publish(models.NewTimerEvent(eventType, timer, time.Now()))
+}
+
+// RelayTimerEvents publishes the timer events notified through the database, such as
+// timers closed by the admin command, to the live update subscribers of this instance
+// until the context is cancelled.
+func RelayTimerEvents(ctx context.Context) {
+	if err := database.ListenTimerEvents(ctx, events.Publish); err != nil {
+		logger.Logger.Error("Timer events from the database are not relayed", zap.Error(err))
+	}
+}
@@ -0,0 +1,140 @@
+// Package service holds the operations that the REST handlers, the gRPC server and,
+// for listings, the GraphQL API all perform, so the APIs validate, store and announce
+// changes the same way. Reads that only query the database call the database package
+// directly.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time-tracker/internal/database"
+	"time-tracker/internal/enrichment"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+)
+
+var ErrUserExists = errors.New("user already exists")
+
+// CreateUser adds the holder of a passport, filling in the rest from the people info
+// service, and returns the stored user. An invalid passport number is reported as
+// validation.Errors for the field named passportField. When the user already exists
+// it returns ErrUserExists with a user holding just the normalized passport number.
+func CreateUser(actor models.Actor, passportField, passportNumber string) (models.User, error) {
+	passport, fieldErr := validation.NormalizePassport(passportField, passportNumber)
+	if fieldErr != nil {
+		return models.User{}, validation.Errors{*fieldErr}
+	}
+
+	exist, err := database.CheckUserByPassport(passport)
+	if err != nil {
+		return models.User{}, err
+	}
+	if exist {
+		return models.User{PassportNumber: passport}, ErrUserExists
+	}
+
+	user, err := enrichment.Lookup(passport)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user.ID, err = database.SaveUser(actor, user)
+	if errors.Is(err, database.ErrPassportExists) {
+		// Another request created the user since the check above.
+		return models.User{PassportNumber: passport}, ErrUserExists
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	// Reload the user for the version assigned by the database.
+	return database.GetUser(user.ID)
+}
+
+// ErrVersionRequired is returned when a user is updated or deleted without the version
+// the change was based on, which would let it silently overwrite changes made since.
+var ErrVersionRequired = errors.New("version is required")
+
+// ErrInvalidFilter is returned, wrapped with the reason, for a users filter that
+// cannot be applied.
+var ErrInvalidFilter = errors.New("invalid users filter")
+
+// AnyVersion is passed as the version by the deprecated REST routes, which keep
+// changing users unconditionally for existing clients.
+const AnyVersion = -1
+
+// UpdateUser applies a partial update and returns the updated user. The version must
+// be the one the client read, or ErrVersionRequired is returned; if the user has
+// changed since, the result is database.ErrVersionMismatch. Invalid fields are
+// reported as validation.Errors, with the passport number named passportField, and a
+// passport number of another user as ErrUserExists with a user holding that number.
+func UpdateUser(actor models.Actor, userID, version int, update models.UserUpdate, passportField string) (models.User, error) {
+	if update.Empty() {
+		return models.User{}, database.ErrEmptyUpdate
+	}
+	if errs := update.Validate(passportField); len(errs) > 0 {
+		return models.User{}, errs
+	}
+	version, err := changeVersion(version)
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := database.UpdateUser(actor, userID, version, update)
+	if errors.Is(err, database.ErrPassportExists) {
+		return models.User{PassportNumber: update.PassportNumber.Value}, ErrUserExists
+	}
+	return user, err
+}
+
+// DeleteUser deletes a user with all time entries. The version is checked as in
+// UpdateUser.
+func DeleteUser(actor models.Actor, userID, version int) error {
+	version, err := changeVersion(version)
+	if err != nil {
+		return err
+	}
+	return database.DeleteUser(actor, userID, version)
+}
+
+// changeVersion converts the version of a change to the one the database checks, where
+// 0 makes the change unconditional.
+func changeVersion(version int) (int, error) {
+	switch {
+	case version == AnyVersion:
+		return 0, nil
+	case version < 1:
+		return 0, ErrVersionRequired
+	}
+	return version, nil
+}
+
+// UserPage is a page of users together with the number of users matching the filter.
+type UserPage struct {
+	Users []models.User
+	// More reports whether users follow the page.
+	More  bool
+	Total int
+}
+
+// ListUsers returns a page of the users matching the filter. A filter that cannot be
+// applied is reported as ErrInvalidFilter, and a cursor that does not fit it as
+// database.ErrInvalidCursor.
+func ListUsers(filter models.UserFilter, page models.PageRequest) (UserPage, error) {
+	// Stored passport numbers are normalized, so the filter is normalized the same way.
+	// A value that is not a passport number is left as is and simply matches nothing.
+	if passport, fieldErr := validation.NormalizePassport("passportNumber", filter.PassportNumber); fieldErr == nil {
+		filter.PassportNumber = passport
+	}
+	if err := filter.Validate(); err != nil {
+		return UserPage{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	users, more, err := database.GetUsers(filter, page)
+	if err != nil {
+		return UserPage{}, err
+	}
+	total, err := database.CountUsers(filter)
+	if err != nil {
+		return UserPage{}, err
+	}
+	return UserPage{Users: users, More: more, Total: total}, nil
+}
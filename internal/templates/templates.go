@@ -0,0 +1,7 @@
+// Package templates holds the HTML pages the handlers render.
+package templates
+
+import "embed"
+
+//go:embed *.html
+var FS embed.FS
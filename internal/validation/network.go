@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"net"
+	"strings"
+)
+
+// PublicIP reports whether ip may be the target of an outgoing request: loopback,
+// link-local, private, unspecified and multicast addresses reach the server itself or
+// the network it runs in, not a receiver on the internet.
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified())
+}
+
+// PublicHost reports whether a URL host, without the port, may be the target of an
+// outgoing request. Names are checked only when they can only mean this machine; the
+// addresses they resolve to are checked when connecting.
+func PublicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return PublicIP(ip)
+	}
+	return true
+}
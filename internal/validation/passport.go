@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	passportSeriesLength = 4
+	passportNumberLength = 6
+)
+
+// NormalizePassport validates a Russian internal passport number (4-digit series and
+// 6-digit number) and returns it in the stored form "1234 567890". Whitespace between
+// the digits is ignored, so "1234567890" and "12 34 567890" are accepted as well.
+func NormalizePassport(field, passport string) (string, *FieldError) {
+	parts := strings.Fields(passport)
+	digits := strings.Join(parts, "")
+
+	if digits == "" {
+		return "", &FieldError{Field: field, Message: "passport number is required"}
+	}
+	for _, r := range digits {
+		if r > unicode.MaxASCII || !unicode.IsDigit(r) {
+			return "", &FieldError{Field: field, Message: "passport number must contain only digits"}
+		}
+	}
+
+	if len(parts) == 2 && len(digits) != passportSeriesLength+passportNumberLength {
+		if len(parts[0]) != passportSeriesLength {
+			return "", &FieldError{Field: field, Message: "passport series must be 4 digits"}
+		}
+		return "", &FieldError{Field: field, Message: "passport number must be 6 digits"}
+	}
+	if len(digits) != passportSeriesLength+passportNumberLength {
+		return "", &FieldError{Field: field, Message: "passport must consist of a 4-digit series and a 6-digit number"}
+	}
+
+	series, number := digits[:passportSeriesLength], digits[passportSeriesLength:]
+	if series == "0000" {
+		return "", &FieldError{Field: field, Message: "passport series must not be 0000"}
+	}
+	if number == "000000" {
+		return "", &FieldError{Field: field, Message: "passport number must not be 000000"}
+	}
+	return series + " " + number, nil
+}
+
+// SplitPassport splits a normalized passport number into its series and number.
+func SplitPassport(passport string) (series, number string) {
+	return passport[:passportSeriesLength], passport[passportSeriesLength+1:]
+}
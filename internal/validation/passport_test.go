@@ -0,0 +1,36 @@
+package validation
+
+import "testing"
+
+func TestNormalizePassport(t *testing.T) {
+	tests := []struct {
+		passport string
+		want     string
+		message  string
+	}{
+		{"1234 567890", "1234 567890", ""},
+		{"1234567890", "1234 567890", ""},
+		{" 12 34  567890 ", "1234 567890", ""},
+		{"", "", "passport number is required"},
+		{"1234 56789O", "", "passport number must contain only digits"},
+		// Full-width digits are digits to Unicode but not to the passport office.
+		{"1234 ５６７８９０", "", "passport number must contain only digits"},
+		{"123 567890", "", "passport series must be 4 digits"},
+		{"1234 56789", "", "passport number must be 6 digits"},
+		{"123456789", "", "passport must consist of a 4-digit series and a 6-digit number"},
+		{"0000 567890", "", "passport series must not be 0000"},
+		{"1234 000000", "", "passport number must not be 000000"},
+	}
+	for _, tt := range tests {
+		got, fieldErr := NormalizePassport("passportNumber", tt.passport)
+		if tt.message == "" {
+			if fieldErr != nil || got != tt.want {
+				t.Errorf("NormalizePassport(%q) = %q, %v, want %q", tt.passport, got, fieldErr, tt.want)
+			}
+			continue
+		}
+		if fieldErr == nil || fieldErr.Message != tt.message || fieldErr.Field != "passportNumber" {
+			t.Errorf("NormalizePassport(%q) error = %v, want %q", tt.passport, fieldErr, tt.message)
+		}
+	}
+}
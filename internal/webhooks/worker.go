@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+	"time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/validation"
+)
+
+const (
+	pollInterval   = 5 * time.Second
+	batchSize      = 20
+	requestTimeout = 10 * time.Second
+	// The lease outlasts a request, so a claimed delivery is not picked up twice.
+	lease = time.Minute
+
+	maxAttempts  = 8
+	retryBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+
+	maxErrorLength = 500
+
+	purgeInterval = time.Hour
+)
+
+// client refuses to connect to addresses that are not public, whatever the webhook URL
+// or a redirect resolves to, so webhooks cannot be used to reach internal services.
+var client = &http.Client{
+	Timeout: requestTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: requestTimeout, Control: dialPublic}).DialContext,
+	},
+}
+
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !validation.PublicIP(ip) {
+		return fmt.Errorf("refusing to connect to %s: not a public address", host)
+	}
+	return nil
+}
+
+// Run delivers queued webhook events until the context is cancelled. Once an hour it
+// deletes the deliveries that ended more than retention ago.
+func Run(ctx context.Context, retention time.Duration) {
+	logger.Logger.Info("Webhook worker started")
+	defer logger.Logger.Info("Webhook worker stopped")
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		deliverDue()
+		if time.Since(purged) >= purgeInterval {
+			purge(retention)
+			purged = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends due deliveries until none are left.
+func deliverDue() {
+	for {
+		deliveries, err := database.ClaimWebhookDeliveries(batchSize, lease)
+		if err != nil {
+			logger.Logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+			return
+		}
+		for _, delivery := range deliveries {
+			deliver(delivery)
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// purge deletes the deliveries that ended more than retention ago.
+func purge(retention time.Duration) {
+	purged, err := database.PurgeWebhookDeliveries(time.Now().Add(-retention))
+	if err != nil {
+		logger.Logger.Error("Failed to purge webhook deliveries", zap.Error(err))
+	} else if purged > 0 {
+		logger.Logger.Info("Purged old webhook deliveries", zap.Int64("deliveries", purged))
+	}
+}
+
+func deliver(delivery database.PendingDelivery) {
+	statusCode, err := send(delivery)
+	if err == nil {
+		if err := database.CompleteWebhookDelivery(delivery.ID, statusCode); err != nil {
+			logger.Logger.Error("Failed to record webhook delivery", zap.Int("delivery", delivery.ID), zap.Error(err))
+		}
+		return
+	}
+
+	attempt := delivery.Attempts + 1
+	next := nextAttempt(attempt, time.Now())
+	logger.Logger.Warn("Webhook delivery failed",
+		zap.Int("delivery", delivery.ID), zap.Int("webhook", delivery.WebhookID),
+		zap.Int("attempt", attempt), zap.Error(err))
+
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	if err := database.FailWebhookDelivery(delivery.ID, statusCode, message, next); err != nil {
+		logger.Logger.Error("Failed to record webhook delivery", zap.Int("delivery", delivery.ID), zap.Error(err))
+	}
+}
+
+// nextAttempt returns when to retry after the given failed attempt, or the zero time
+// when it was the last one.
+func nextAttempt(attempt int, now time.Time) time.Time {
+	if attempt >= maxAttempts {
+		return time.Time{}
+	}
+	return now.Add(backoff(attempt))
+}
+
+// backoff doubles the delay after every failed attempt, up to maxBackoff.
+func backoff(attempt int) time.Duration {
+	delay := retryBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// send posts the payload and returns the response status. Any status outside 2xx is
+// an error.
+func send(delivery database.PendingDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "time-tracker-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "timestamp.body" keyed with the webhook
+// secret. Receivers recompute it to verify the sender and reject stale timestamps to
+// stop replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
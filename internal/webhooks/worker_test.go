@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"timer.started"}`)
+	// Computed independently as HMAC-SHA256("1700000000." + body).
+	want := "75a387fd66cd169cb302ca4f3f64c38f7f9a3030da0764b55400fe9bbeeb7d96"
+	if got := Sign("whsec_0123456789abcdef", "1700000000", body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		name              string
+		secret, timestamp string
+		body              string
+	}{
+		{"other secret", "whsec_fedcba9876543210", "1700000000", string(body)},
+		{"other timestamp", "whsec_0123456789abcdef", "1700000001", string(body)},
+		{"other body", "whsec_0123456789abcdef", "1700000000", `{"event":"timer.stopped"}`},
+		// The separator keeps digits from moving between the timestamp and the body.
+		{"shifted separator", "whsec_0123456789abcdef", "170000000", `0.{"event":"timer.started"}`},
+	}
+	for _, tt := range tests {
+		if Sign(tt.secret, tt.timestamp, []byte(tt.body)) == want {
+			t.Errorf("%s: signature unchanged", tt.name)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, maxBackoff},
+		{50, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		if got := nextAttempt(attempt, now); !got.Equal(now.Add(backoff(attempt))) {
+			t.Errorf("nextAttempt(%d) = %v, want a retry after %v", attempt, got, backoff(attempt))
+		}
+	}
+	for _, attempt := range []int{maxAttempts, maxAttempts + 1} {
+		if got := nextAttempt(attempt, now); !got.IsZero() {
+			t.Errorf("nextAttempt(%d) = %v, want no retry", attempt, got)
+		}
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"224.0.0.1:80", false},
+		{"example.com:80", false},
+		{"no port", false},
+	}
+	for _, tt := range tests {
+		err := dialPublic("tcp", tt.address, nil)
+		if (err == nil) != tt.allowed {
+			t.Errorf("dialPublic(%q) = %v, want allowed %v", tt.address, err, tt.allowed)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("posting to %s: got %v, want the connection refused", server.URL, err)
+	}
+}